@@ -12,8 +12,7 @@ package client
 import (
 	//"fmt"
 	"log"
-	//"net/url"
-	"reflect"
+	neturl "net/url"
 	"strings"
 	"sync"
 	"time"
@@ -35,8 +34,13 @@ const (
 // Client implementations sends service requests to the Danube Cloud.
 type Client interface {
 	SendRequest(method, apiCall, rfc1123Date string, request *danubehttp.RequestData, response *danubehttp.ResponseData) (err error)
+	// SendBinaryRequest streams request.ReqReader as the request body. The request is not retried.
+	SendBinaryRequest(method, apiCall, rfc1123Date string, request *danubehttp.RequestData, response *danubehttp.ResponseData) (err error)
 	SwitchVirtDC(virtDC string)
 	GetVirtDC() string
+	// WithVirtDC returns a client that sends all requests to the virtual datacenter virtDC.
+	// The returned client shares the HTTP connection pool and rate limiter with the original.
+	WithVirtDC(virtDC string) Client
 	SetTrace(traceEnabled bool)
 	GetTrace() bool
 	// MakeServiceURL prepares a full URL to a service endpoint, with optional
@@ -56,6 +60,12 @@ type client struct {
 
 var _ Client = (*client)(nil)
 
+// VirtDCSetter is implemented by request data that carry the virtual datacenter name.
+// The client fills in its current virtual datacenter if the request does not specify one.
+type VirtDCSetter interface {
+	SetDefaultVirtDC(virtDC string)
+}
+
 func newClient(credentials *auth.Credentials, httpClient *danubehttp.Client, logger *log.Logger) Client {
 	client := client{creds: credentials, logger: logger, httpClient: httpClient}
 	return &client
@@ -82,35 +92,34 @@ func (c *client) sendRequest(method, url, rfc1123Date string, request *danubehtt
 func (c *client) SendRequest(method, apiCall, rfc1123Date string, request *danubehttp.RequestData, response *danubehttp.ResponseData) (err error) {
 	//DELME url := c.MakeServiceURL([]string{c.creds.UserAuthentication.User, apiCall})
 	url := makeURL(c.creds.ApiEndpoint.URL, []string{apiCall})
-	if c.creds.VirtDatacenter != "" {
+	if virtDC := c.GetVirtDC(); virtDC != "" {
 		if request.Params != nil && request.Params.Get("dc") == "" {
 			// set default VirtDatacenter in GET params
-			request.Params.Set("dc", c.creds.VirtDatacenter)
+			request.Params.Set("dc", virtDC)
 		}
-		//if request.ReqValue != nil && request.ReqValue.Dc == "" {
-		if request.ReqValue != nil {
+		if s, ok := request.ReqValue.(VirtDCSetter); ok {
 			// set default VirtDatacenter in data json (non-GET call)
-			s := reflect.ValueOf(&request.ReqValue)
-			/**
-			The most common source of problems here is passing an object that has no 'Dc' member.
-			(means ReqData is not inherited).
-			*/
-			//println(s.String())                      // should return <*interface {} Value>
-			//println(s.Elem().String())               // should return <interface {} Value>
-			//println(s.Elem().Elem().String())        // should return <*cloudapi. ... Value>
-			//println(s.Elem().Elem().Elem().String()) // should return <cloudapi. ... Value>
-			s = s.Elem().Elem().Elem()
-			req_dc := s.FieldByName("Dc").Interface()
-			//fmt.Println(req_dc)
-			if req_dc == "" {
-				s.FieldByName("Dc").SetString(c.creds.VirtDatacenter)
-			}
+			s.SetDefaultVirtDC(virtDC)
 		}
 	}
 	err = c.sendRequest(method, url, rfc1123Date, request, response)
 	return err
 }
 
+func (c *client) SendBinaryRequest(method, apiCall, rfc1123Date string, request *danubehttp.RequestData, response *danubehttp.ResponseData) (err error) {
+	url := makeURL(c.creds.ApiEndpoint.URL, []string{apiCall})
+	if virtDC := c.GetVirtDC(); virtDC != "" {
+		// there is no JSON body, so the VirtDatacenter always goes to the URL params
+		if request.Params == nil {
+			request.Params = &neturl.Values{}
+		}
+		if request.Params.Get("dc") == "" {
+			request.Params.Set("dc", virtDC)
+		}
+	}
+	return c.httpClient.BinaryRequest(method, url, rfc1123Date, request, response)
+}
+
 func makeURL(base string, parts []string) string {
 	if !strings.HasSuffix(base, "/") && len(parts) > 0 {
 		base += "/"
@@ -156,14 +165,28 @@ func (c *client) SignURL(path string, expires time.Time) (string, error) {
 	*/
 }
 
+// SwitchVirtDC changes the virtual datacenter used by all subsequent requests of this client.
+// Use WithVirtDC when working with more virtual datacenters concurrently.
 func (c *client) SwitchVirtDC(virtDC string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.creds.VirtDatacenter = virtDC
 }
 
 func (c *client) GetVirtDC() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.creds.VirtDatacenter
 }
 
+func (c *client) WithVirtDC(virtDC string) Client {
+	c.mu.Lock()
+	creds := *c.creds
+	c.mu.Unlock()
+	creds.VirtDatacenter = virtDC
+	return newClient(&creds, c.httpClient, c.logger)
+}
+
 func (c *client) SetTrace(traceEnabled bool) {
 	c.httpClient.SetTrace(traceEnabled)
 }
@@ -8,6 +8,7 @@ Copyright (c) Joyent Inc.
 package cloudapi
 
 import (
+	"io"
 	"net/http"
 	"net/url"
 	"path"
@@ -26,9 +27,7 @@ const (
 	apiImages                  = "images"
 	apiDatacenters             = "datacenters"
 	apiMachines                = "machines"
-	apiMetadata                = "metadata"
 	apiSnapshots               = "snapshots"
-	apiTags                    = "tags"
 	apiAnalytics               = "analytics"
 	apiInstrumentations        = "instrumentations"
 	apiInstrumentationsValue   = "value"
@@ -42,8 +41,6 @@ const (
 	apiFirewallRulesEnable     = "enable"
 	apiFirewallRulesDisable    = "disable"
 	apiNetworks                = "networks"
-	apiFabricVLANs             = "fabrics/default/vlans"
-	apiFabricNetworks          = "networks"
 	apiNICs                    = "nics"
 	apiServices                = "services"
 
@@ -52,7 +49,6 @@ const (
 	actionStop      = "stop"
 	actionStart     = "start"
 	actionReboot    = "reboot"
-	actionResize    = "resize"
 	actionRename    = "rename"
 	actionEnableFw  = "enable_firewall"
 	actionDisableFw = "disable_firewall"
@@ -103,6 +99,8 @@ type request struct {
 	filter           *Filter
 	reqValue         interface{}
 	reqHeader        http.Header
+	reqReader        io.Reader // body of binary requests
+	reqLength        int64     // number of bytes in reqReader
 	resp             interface{}
 	respHeader       *http.Header
 	expectedStatus   int
@@ -139,6 +137,16 @@ type ReqData struct {
 	Force bool   `json:"force,omitempty"`
 }
 
+var _ client.VirtDCSetter = (*ReqData)(nil)
+
+// SetDefaultVirtDC sets the virtual datacenter of the request unless it is already set.
+// Implements client.VirtDCSetter, so every request struct embedding ReqData gets the vDC of the client.
+func (r *ReqData) SetDefaultVirtDC(virtDC string) {
+	if r.Dc == "" {
+		r.Dc = virtDC
+	}
+}
+
 /*
 type StatusQuery struct {
 	Url		string
@@ -212,15 +220,53 @@ func (c *Client) sendRequest(req request) (*jh.ResponseData, error) {
 	return &respData, err
 }
 
+// Helper method to send an API request with binary data from req.reqReader as the body
+func (c *Client) sendBinaryRequest(req request) (*jh.ResponseData, error) {
+	request := jh.RequestData{
+		ReqHeaders: req.reqHeader,
+		ReqReader:  req.reqReader,
+		ReqLength:  req.reqLength,
+	}
+	if req.filter != nil {
+		request.Params = &req.filter.v
+	}
+
+	if len(req.expectedStatuses) == 0 {
+		req.expectedStatuses = []int{http.StatusOK}
+	}
+	respData := jh.ResponseData{
+		RespValue:      req.resp,
+		RespHeaders:    req.respHeader,
+		ExpectedStatus: req.expectedStatuses,
+	}
+
+	err := c.client.SendBinaryRequest(req.method, req.url, "", &request, &respData)
+	return &respData, err
+}
+
 // Helper method to create the API URL
 func makeURL(parts ...string) string {
 	return path.Join(parts...)
 }
 
+// SwitchVirtDC changes the virtual datacenter for all subsequent calls of this client
+// and of all goroutines sharing it. Use WithVirtDC for a per-goroutine vDC.
 func (c *Client) SwitchVirtDC(virtDC string) {
 	c.client.SwitchVirtDC(virtDC)
 }
 
+// GetVirtDC returns the virtual datacenter the client is working in.
+func (c *Client) GetVirtDC() string {
+	return c.client.GetVirtDC()
+}
+
+// WithVirtDC returns a lightweight view of the client that executes all calls
+// in the virtual datacenter virtDC. The original client is not modified and both
+// share the HTTP connection pool and rate limiter, so they can be used concurrently.
+func (c *Client) WithVirtDC(virtDC string) *Client {
+	return &Client{c.client.WithVirtDC(virtDC)}
+}
+
 func (c *Client) SetTrace(traceEnabled bool) {
 	c.client.SetTrace(traceEnabled)
 }
@@ -7,35 +7,335 @@ import (
 	"github.com/erigones/godanube/errors"
 )
 
-// ListDatacenters provides a list of all datacenters this cloud is aware of.
-// See API docs: http://apidocs.joyent.com/cloudapi/#ListDatacenters
-func (c *Client) ListDatacenters() (map[string]interface{}, error) {
-	var resp map[string]interface{}
+const (
+	// node resource strategies in a virtual datacenter
+	DcNodeStrategyShared      = 1
+	DcNodeStrategySharedLimit = 2
+	DcNodeStrategyReserved    = 3
+
+	dcAttachTimeout = 120 / TaskQuerySleepTime
+)
+
+// VirtDatacenter represents a Danube Cloud virtual datacenter (vDC)
+// https://docs.danubecloud.org/api-reference/api/dc_base.html
+type VirtDatacenter struct {
+	ReqData
+	GenericDcEntity          // contains name, alias, owner, access, desc, etc.
+	SiteLink        string   `json:"site_link,omitempty"` // Link to the vDC GUI site
+	Groups          []string `json:"groups,omitempty"`    // User groups (roles) attached to the vDC
+}
+
+// DcNode represents a compute node attached to a virtual datacenter
+type DcNode struct {
+	ReqData
+	Hostname string `json:"hostname,omitempty"`
+	Strategy int    `json:"strategy,omitempty"` // one of DcNodeStrategy*
+	Cpu      int    `json:"cpu,omitempty"`      // reserved vCPUs (strategy 2 and 3)
+	Ram      int    `json:"ram,omitempty"`      // reserved RAM in MB (strategy 2 and 3)
+	Disk     int    `json:"disk,omitempty"`     // reserved local disk in MB (strategy 2 and 3)
+	Priority int    `json:"priority,omitempty"` // higher number means higher priority for automatic VM placement
+
+	// Not settable, only for querying:
+	CpuFree  int `json:"cpu_free,omitempty"`
+	RamFree  int `json:"ram_free,omitempty"`
+	DiskFree int `json:"disk_free,omitempty"`
+}
+
+/*** STRUCTS FOR VDC-SPECIFIC DC RESPONSES ***/
+type VirtDatacenterResponse struct {
+	DcResponse
+	Result VirtDatacenter `json:"result"`
+}
+
+type VirtDatacenterResponseFull struct {
+	DcResponse
+	Result []VirtDatacenter `json:"result"`
+}
+
+type DcNodeResponse struct {
+	DcResponse
+	Result DcNode `json:"result"`
+}
+
+type DcNodeResponseFull struct {
+	DcResponse
+	Result []DcNode `json:"result"`
+}
+
+// ListDatacenters returns names of all virtual datacenters visible to the user.
+func (c *Client) ListDatacenters() ([]string, error) {
+	var resp ResponseList
+	req := request{
+		method: client.GET,
+		url:    "dc",
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of virtual datacenters")
+	}
+	return resp.Result, nil
+}
+
+// ListDatacentersFull returns details of all virtual datacenters visible to the user.
+func (c *Client) ListDatacentersFull() ([]VirtDatacenter, error) {
+	var resp VirtDatacenterResponseFull
+	filter := NewFilter()
+	filter.Set("full", "true")
+	req := request{
+		method: client.GET,
+		url:    "dc",
+		filter: filter,
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of virtual datacenters")
+	}
+	return resp.Result, nil
+}
+
+// GetDatacenter returns details of a virtual datacenter.
+func (c *Client) GetDatacenter(dcName string) (*VirtDatacenter, error) {
+	var resp VirtDatacenterResponse
+	req := request{
+		method: client.GET,
+		url:    makeURL("dc", dcName),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get virtual datacenter \"%s\"", dcName)
+	}
+	return &resp.Result, nil
+}
+
+// CreateDatacenter creates a new virtual datacenter. Needs SuperAdmin rights.
+func (c *Client) CreateDatacenter(opts VirtDatacenter) (*VirtDatacenter, error) {
+	var resp VirtDatacenterResponse
+	req := request{
+		method:           client.POST,
+		url:              makeURL("dc", opts.Name),
+		reqValue:         &opts,
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to create virtual datacenter \"%s\"", opts.Name)
+	}
+	return &resp.Result, nil
+}
+
+// UpdateDatacenter changes the settable attributes of a virtual datacenter.
+// Empty fields are left unchanged.
+func (c *Client) UpdateDatacenter(opts VirtDatacenter) (*VirtDatacenter, error) {
+	var resp VirtDatacenterResponse
+	req := request{
+		method:   client.PUT,
+		url:      makeURL("dc", opts.Name),
+		reqValue: &opts,
+		resp:     &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to update virtual datacenter \"%s\"", opts.Name)
+	}
+	return &resp.Result, nil
+}
+
+// DeleteDatacenter deletes a virtual datacenter. The vDC must not contain any VMs.
+func (c *Client) DeleteDatacenter(dcName string) error {
+	var resp DcResponse
+	req := request{
+		method: client.DELETE,
+		url:    makeURL("dc", dcName),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return errors.Newf2(err, resp.Detail, "failed to delete virtual datacenter \"%s\"", dcName)
+	}
+	return nil
+}
+
+// Helper method to attach an object (node, network, image, ...) to a virtual datacenter.
+// objType is the URL part used by the API (e.g. "network").
+func (c *Client) attachToDatacenter(dcName, objType, objName string, opts interface{}) error {
+	var resp DcResponse
+	req := request{
+		method:           client.POST,
+		url:              makeURL("dc", dcName, objType, objName),
+		reqValue:         opts,
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return errors.Newf2(err, resp.Detail, "failed to attach %s \"%s\" to virtual datacenter \"%s\"", objType, objName, dcName)
+	}
+	if err := c.waitForOptionalTask(&resp, dcAttachTimeout, req.expectedStatuses); err != nil {
+		return errors.Newf(err, "failed to attach %s \"%s\" to virtual datacenter \"%s\"", objType, objName, dcName)
+	}
+	return nil
+}
+
+// Helper method to detach an object from a virtual datacenter.
+func (c *Client) detachFromDatacenter(dcName, objType, objName string) error {
+	var resp DcResponse
+	req := request{
+		method:           client.DELETE,
+		url:              makeURL("dc", dcName, objType, objName),
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return errors.Newf2(err, resp.Detail, "failed to detach %s \"%s\" from virtual datacenter \"%s\"", objType, objName, dcName)
+	}
+	if err := c.waitForOptionalTask(&resp, dcAttachTimeout, req.expectedStatuses); err != nil {
+		return errors.Newf(err, "failed to detach %s \"%s\" from virtual datacenter \"%s\"", objType, objName, dcName)
+	}
+	return nil
+}
+
+// Helper method to list names of objects of one type attached to a virtual datacenter.
+func (c *Client) listAttachedToDatacenter(dcName, objType string) ([]string, error) {
+	var resp ResponseList
 	req := request{
 		method: client.GET,
-		url:    apiDatacenters,
+		url:    makeURL("dc", dcName, objType),
 		resp:   &resp,
 	}
 	if _, err := c.sendRequest(req); err != nil {
-		return nil, errors.Newf(err, "failed to get list of datcenters")
+		return nil, errors.Newf2(err, resp.Detail, "failed to list %s objects in virtual datacenter \"%s\"", objType, dcName)
 	}
-	return resp, nil
+	return resp.Result, nil
 }
 
-// GetDatacenter gets an individual datacenter by name. Returns an HTTP redirect
-// to your client, the datacenter URL is in the Location header.
-// See API docs: http://apidocs.joyent.com/cloudapi/#GetDatacenter
-func (c *Client) GetDatacenter(datacenterName string) (string, error) {
-	var respHeader http.Header
+// GetDatacenterNodes returns compute nodes attached to a virtual datacenter
+// together with their resource reservations.
+func (c *Client) GetDatacenterNodes(dcName string) ([]DcNode, error) {
+	var resp DcNodeResponseFull
+	filter := NewFilter()
+	filter.Set("full", "true")
 	req := request{
-		method:         client.GET,
-		url:            makeURL(apiDatacenters, datacenterName),
-		respHeader:     &respHeader,
-		expectedStatus: http.StatusFound,
+		method: client.GET,
+		url:    makeURL("dc", dcName, "node"),
+		filter: filter,
+		resp:   &resp,
 	}
-	respData, err := c.sendRequest(req)
-	if err != nil {
-		return "", errors.Newf(err, "failed to get datacenter with name: %s", datacenterName)
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get nodes in virtual datacenter \"%s\"", dcName)
 	}
-	return respData.RespHeaders.Get("Location"), nil
+	return resp.Result, nil
+}
+
+// AttachNode attaches a compute node to a virtual datacenter.
+func (c *Client) AttachNode(dcName string, opts DcNode) error {
+	return c.attachToDatacenter(dcName, "node", opts.Hostname, &opts)
+}
+
+// UpdateNodeAttachment changes resource reservations of a node attached to a virtual datacenter.
+func (c *Client) UpdateNodeAttachment(dcName string, opts DcNode) (*DcNode, error) {
+	var resp DcNodeResponse
+	req := request{
+		method:   client.PUT,
+		url:      makeURL("dc", dcName, "node", opts.Hostname),
+		reqValue: &opts,
+		resp:     &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to update node \"%s\" in virtual datacenter \"%s\"", opts.Hostname, dcName)
+	}
+	return &resp.Result, nil
+}
+
+// DetachNode detaches a compute node from a virtual datacenter.
+func (c *Client) DetachNode(dcName, nodeHostname string) error {
+	return c.detachFromDatacenter(dcName, "node", nodeHostname)
+}
+
+// ListDatacenterStorages returns storages (in "zpool@node" format) attached to a virtual datacenter.
+func (c *Client) ListDatacenterStorages(dcName string) ([]string, error) {
+	return c.listAttachedToDatacenter(dcName, "storage")
+}
+
+// AttachStorage attaches a node storage to a virtual datacenter.
+// Storage is identified as "zpool@node-hostname".
+func (c *Client) AttachStorage(dcName, storage string) error {
+	return c.attachToDatacenter(dcName, "storage", storage, nil)
+}
+
+// DetachStorage detaches a node storage from a virtual datacenter.
+func (c *Client) DetachStorage(dcName, storage string) error {
+	return c.detachFromDatacenter(dcName, "storage", storage)
+}
+
+// ListDatacenterNetworks returns names of networks attached to a virtual datacenter.
+func (c *Client) ListDatacenterNetworks(dcName string) ([]string, error) {
+	return c.listAttachedToDatacenter(dcName, "network")
+}
+
+// AttachNetwork attaches a network to a virtual datacenter.
+func (c *Client) AttachNetwork(dcName, networkName string) error {
+	return c.attachToDatacenter(dcName, "network", networkName, nil)
+}
+
+// DetachNetwork detaches a network from a virtual datacenter.
+func (c *Client) DetachNetwork(dcName, networkName string) error {
+	return c.detachFromDatacenter(dcName, "network", networkName)
+}
+
+// ListDatacenterImages returns names of images attached to a virtual datacenter.
+func (c *Client) ListDatacenterImages(dcName string) ([]string, error) {
+	return c.listAttachedToDatacenter(dcName, "image")
+}
+
+// AttachImage attaches an image to a virtual datacenter,
+// which makes it available for deployment in the vDC.
+func (c *Client) AttachImage(dcName, imageName string) error {
+	return c.attachToDatacenter(dcName, "image", imageName, nil)
+}
+
+// DetachImage detaches an image from a virtual datacenter.
+func (c *Client) DetachImage(dcName, imageName string) error {
+	return c.detachFromDatacenter(dcName, "image", imageName)
+}
+
+// ListDatacenterTemplates returns names of VM templates attached to a virtual datacenter.
+func (c *Client) ListDatacenterTemplates(dcName string) ([]string, error) {
+	return c.listAttachedToDatacenter(dcName, "template")
+}
+
+// AttachTemplate attaches a VM template to a virtual datacenter.
+func (c *Client) AttachTemplate(dcName, templateName string) error {
+	return c.attachToDatacenter(dcName, "template", templateName, nil)
+}
+
+// DetachTemplate detaches a VM template from a virtual datacenter.
+func (c *Client) DetachTemplate(dcName, templateName string) error {
+	return c.detachFromDatacenter(dcName, "template", templateName)
+}
+
+// ListDatacenterIsos returns names of ISO images attached to a virtual datacenter.
+func (c *Client) ListDatacenterIsos(dcName string) ([]string, error) {
+	return c.listAttachedToDatacenter(dcName, "iso")
+}
+
+// AttachIso attaches an ISO image to a virtual datacenter.
+func (c *Client) AttachIso(dcName, isoName string) error {
+	return c.attachToDatacenter(dcName, "iso", isoName, nil)
+}
+
+// DetachIso detaches an ISO image from a virtual datacenter.
+func (c *Client) DetachIso(dcName, isoName string) error {
+	return c.detachFromDatacenter(dcName, "iso", isoName)
+}
+
+// ListDatacenterDomains returns names of DNS domains attached to a virtual datacenter.
+func (c *Client) ListDatacenterDomains(dcName string) ([]string, error) {
+	return c.listAttachedToDatacenter(dcName, "domain")
+}
+
+// AttachDomain attaches a DNS domain to a virtual datacenter.
+func (c *Client) AttachDomain(dcName, domainName string) error {
+	return c.attachToDatacenter(dcName, "domain", domainName, nil)
+}
+
+// DetachDomain detaches a DNS domain from a virtual datacenter.
+func (c *Client) DetachDomain(dcName, domainName string) error {
+	return c.detachFromDatacenter(dcName, "domain", domainName)
 }
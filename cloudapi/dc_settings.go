@@ -0,0 +1,332 @@
+package cloudapi
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/erigones/godanube/client"
+	"github.com/erigones/godanube/errors"
+)
+
+// DcSettings represents settings of a virtual datacenter.
+// Global settings (marked as such) can only be read and changed in the default (main) vDC.
+// Secrets and global settings are tagged with dcsetting:"secret" and dcsetting:"global",
+// see DcSettingsOpts.
+// https://docs.danubecloud.org/api-reference/api/dc_settings.html
+type DcSettings struct {
+	// Feature toggles
+	VmsZoneEnabled       bool `json:"VMS_ZONE_ENABLED"`
+	VmsVmSnapshotEnabled bool `json:"VMS_VM_SNAPSHOT_ENABLED"`
+	VmsVmBackupEnabled   bool `json:"VMS_VM_BACKUP_ENABLED"`
+	VmsVmReplicaEnabled  bool `json:"VMS_VM_REPLICATION_ENABLED"`
+	VmsTemplateEnabled   bool `json:"VMS_TEMPLATE_ENABLED"`
+	VmsIsoEnabled        bool `json:"VMS_ISO_ENABLED"`
+	MonZabbixEnabled     bool `json:"MON_ZABBIX_ENABLED"`
+	DnsEnabled           bool `json:"DNS_ENABLED"`
+	SupportEnabled       bool `json:"SUPPORT_ENABLED"`
+	SmsEnabled           bool `json:"SMS_ENABLED"`
+
+	// VM defaults
+	VmsVmOstypeDefault        int               `json:"VMS_VM_OSTYPE_DEFAULT"`
+	VmsVmMonitoredDefault     bool              `json:"VMS_VM_MONITORED_DEFAULT"`
+	VmsVmCpuSharesDefault     int               `json:"VMS_VM_CPU_SHARES_DEFAULT"`
+	VmsVmZfsIoPriorityDefault int               `json:"VMS_VM_ZFS_IO_PRIORITY_DEFAULT"`
+	VmsVmCpuTypeDefault       string            `json:"VMS_VM_CPU_TYPE_DEFAULT"`
+	VmsVmResolversDefault     []string          `json:"VMS_VM_RESOLVERS_DEFAULT"`
+	VmsVmDomainDefault        string            `json:"VMS_VM_DOMAIN_DEFAULT"`
+	VmsVmSshKeysDefault       []string          `json:"VMS_VM_SSH_KEYS_DEFAULT"`
+	VmsVmMdataDefault         map[string]string `json:"VMS_VM_MDATA_DEFAULT"`
+	VmsVmStopTimeoutDefault   int               `json:"VMS_VM_STOP_TIMEOUT_DEFAULT"`
+	VmsVmStopWinTimeout       int               `json:"VMS_VM_STOP_WIN_TIMEOUT_DEFAULT"`
+	VmsVgaModelDefault        string            `json:"VMS_VGA_MODEL_DEFAULT"`
+	VmsDiskModelDefault       string            `json:"VMS_DISK_MODEL_DEFAULT"`
+	VmsDiskCompressionDefault string            `json:"VMS_DISK_COMPRESSION_DEFAULT"`
+	VmsDiskImageDefault       string            `json:"VMS_DISK_IMAGE_DEFAULT"`
+	VmsDiskImageZoneDefault   string            `json:"VMS_DISK_IMAGE_ZONE_DEFAULT"`
+	VmsNicModelDefault        string            `json:"VMS_NIC_MODEL_DEFAULT"`
+	VmsNicMonitoringDefault   int               `json:"VMS_NIC_MONITORING_DEFAULT"`
+	VmsNetDefault             string            `json:"VMS_NET_DEFAULT"`
+	VmsStorageDefault         string            `json:"VMS_STORAGE_DEFAULT"`
+
+	// Snapshot and backup limits (empty/zero means unlimited)
+	VmsVmSnapshotDefineLimit      int `json:"VMS_VM_SNAPSHOT_DEFINE_LIMIT"`
+	VmsVmSnapshotLimitAuto        int `json:"VMS_VM_SNAPSHOT_LIMIT_AUTO"`
+	VmsVmSnapshotLimitManual      int `json:"VMS_VM_SNAPSHOT_LIMIT_MANUAL"`
+	VmsVmSnapshotLimitManualDef   int `json:"VMS_VM_SNAPSHOT_LIMIT_MANUAL_DEFAULT"`
+	VmsVmSnapshotSizeLimit        int `json:"VMS_VM_SNAPSHOT_SIZE_LIMIT"`
+	VmsVmSnapshotSizeLimitDefault int `json:"VMS_VM_SNAPSHOT_SIZE_LIMIT_DEFAULT"`
+	VmsVmSnapshotDcSizeLimit      int `json:"VMS_VM_SNAPSHOT_DC_SIZE_LIMIT"`
+	VmsVmBackupDefineLimit        int `json:"VMS_VM_BACKUP_DEFINE_LIMIT"`
+	VmsVmBackupLimit              int `json:"VMS_VM_BACKUP_LIMIT"`
+	VmsVmBackupDcSizeLimit        int `json:"VMS_VM_BACKUP_DC_SIZE_LIMIT"`
+	VmsVmBackupCompressionDefault int `json:"VMS_VM_BACKUP_COMPRESSION_DEFAULT"`
+
+	// Monitoring
+	MonZabbixServer          string   `json:"MON_ZABBIX_SERVER"`
+	MonZabbixUsername        string   `json:"MON_ZABBIX_USERNAME"`
+	MonZabbixPassword        string   `json:"MON_ZABBIX_PASSWORD" dcsetting:"secret"`
+	MonZabbixHttpUsername    string   `json:"MON_ZABBIX_HTTP_USERNAME"`
+	MonZabbixHttpPassword    string   `json:"MON_ZABBIX_HTTP_PASSWORD" dcsetting:"secret"`
+	MonZabbixHostgroupVm     string   `json:"MON_ZABBIX_HOSTGROUP_VM"`
+	MonZabbixHostgroupsVm    []string `json:"MON_ZABBIX_HOSTGROUPS_VM"`
+	MonZabbixHostgroupsVmRes []string `json:"MON_ZABBIX_HOSTGROUPS_VM_RESTRICT"`
+	MonZabbixTemplatesVm     []string `json:"MON_ZABBIX_TEMPLATES_VM"`
+	MonZabbixTemplatesVmRes  []string `json:"MON_ZABBIX_TEMPLATES_VM_RESTRICT"`
+	MonZabbixVmSync          bool     `json:"MON_ZABBIX_VM_SYNC"`
+
+	// DNS
+	DnsPtrDefault  string   `json:"DNS_PTR_DEFAULT"`
+	DnsNameservers []string `json:"DNS_NAMESERVERS"`
+	DnsHostmaster  string   `json:"DNS_HOSTMASTER"`
+	DnsSoaDefault  string   `json:"DNS_SOA_DEFAULT"`
+
+	// Global settings (main vDC only)
+	VmsImageVm           string            `json:"VMS_IMAGE_VM,omitempty" dcsetting:"global"`
+	VmsImageRepositories map[string]string `json:"VMS_IMAGE_REPOSITORIES,omitempty" dcsetting:"global"`
+	VmsImageSources      []string          `json:"VMS_IMAGE_SOURCES,omitempty" dcsetting:"global"`
+}
+
+// DcSettingsChanges holds a partial settings update keyed by setting name
+// (e.g. "VMS_VM_DOMAIN_DEFAULT"). Only the present keys are changed by the API.
+type DcSettingsChanges map[string]interface{}
+
+// DcSettingsOpts selects the settings compared, exported and imported by DiffDcSettings(),
+// SetDcSettings(), ExportDcSettings() and ImportDcSettings(). By default secrets
+// (passwords) and global settings are left out.
+type DcSettingsOpts struct {
+	Secrets bool // include secrets, e.g. MON_ZABBIX_PASSWORD
+	Global  bool // include global settings (VMS_IMAGE_*); ignored for vDCs other than main
+}
+
+// Helper that tells whether a DcSettings field is selected by the options.
+func (opts DcSettingsOpts) includes(f reflect.StructField) bool {
+	switch f.Tag.Get("dcsetting") {
+	case "secret":
+		return opts.Secrets
+	case "global":
+		return opts.Global
+	}
+	return true
+}
+
+// Helper that returns the options usable with a vDC (global settings exist only in main).
+func (opts DcSettingsOpts) forDc(dcName string) DcSettingsOpts {
+	if dcName != mainVirtDC {
+		opts.Global = false
+	}
+	return opts
+}
+
+/*** STRUCTS FOR SETTINGS-SPECIFIC DC RESPONSES ***/
+type DcSettingsResponse struct {
+	DcResponse
+	Result DcSettings `json:"result"`
+}
+
+// dcSettingsRequest wraps the partial update so that the vDC name
+// can be injected the same way as with other requests.
+type dcSettingsRequest struct {
+	ReqData
+	Changes DcSettingsChanges `json:"-"`
+}
+
+// MarshalJSON sends the changes as a flat JSON object.
+func (r dcSettingsRequest) MarshalJSON() ([]byte, error) {
+	data := make(map[string]interface{}, len(r.Changes)+1)
+	for k, v := range r.Changes {
+		data[k] = v
+	}
+	if r.Dc != "" {
+		data["dc"] = r.Dc
+	}
+	return json.Marshal(data)
+}
+
+// GetDcSettings returns settings of a virtual datacenter.
+func (c *Client) GetDcSettings(dcName string) (*DcSettings, error) {
+	var resp DcSettingsResponse
+	req := request{
+		method: client.GET,
+		url:    makeURL("dc", dcName, "settings"),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get settings of virtual datacenter \"%s\"", dcName)
+	}
+	return &resp.Result, nil
+}
+
+// UpdateDcSettings changes only the settings present in changes
+// and returns the resulting settings of the virtual datacenter.
+func (c *Client) UpdateDcSettings(dcName string, changes DcSettingsChanges) (*DcSettings, error) {
+	if err := checkDcSettingsKeys(changes); err != nil {
+		return nil, err
+	}
+	var resp DcSettingsResponse
+	req := request{
+		method:   client.PUT,
+		url:      makeURL("dc", dcName, "settings"),
+		reqValue: &dcSettingsRequest{Changes: changes},
+		resp:     &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to update settings of virtual datacenter \"%s\"", dcName)
+	}
+	return &resp.Result, nil
+}
+
+// SetDcSettings makes the settings of a virtual datacenter selected by opts equal to desired.
+// Only the settings that differ from the current state are sent.
+func (c *Client) SetDcSettings(dcName string, desired DcSettings, opts DcSettingsOpts) (*DcSettings, error) {
+	current, err := c.GetDcSettings(dcName)
+	if err != nil {
+		return nil, err
+	}
+	changes := DiffDcSettings(*current, desired, opts.forDc(dcName))
+	if len(changes) == 0 {
+		return current, nil
+	}
+	return c.UpdateDcSettings(dcName, changes)
+}
+
+// DiffDcSettings returns the settings selected by opts from b that differ from a.
+// Applying the result to a vDC with settings a makes them equal to b.
+// Empty and missing (nil) lists and maps are considered equal.
+func DiffDcSettings(a, b DcSettings, opts DcSettingsOpts) DcSettingsChanges {
+	changes := make(DcSettingsChanges)
+	va := reflect.ValueOf(a)
+	vb := reflect.ValueOf(b)
+	t := va.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := dcSettingName(t.Field(i))
+		if name == "" || !opts.includes(t.Field(i)) {
+			continue
+		}
+		fa, fb := va.Field(i), vb.Field(i)
+		if isEmptyCollection(fa) && isEmptyCollection(fb) {
+			continue
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			changes[name] = fb.Interface()
+		}
+	}
+	return changes
+}
+
+// DiffDcSettingsBetween compares settings of two virtual datacenters.
+// The result contains settings of dcB that differ from dcA.
+// Global settings are never compared, because they exist only in the main vDC.
+func (c *Client) DiffDcSettingsBetween(dcA, dcB string, opts DcSettingsOpts) (DcSettingsChanges, error) {
+	a, err := c.GetDcSettings(dcA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := c.GetDcSettings(dcB)
+	if err != nil {
+		return nil, err
+	}
+	opts.Global = false
+	return DiffDcSettings(*a, *b, opts), nil
+}
+
+// ExportDcSettings writes settings of a virtual datacenter selected by opts
+// as indented JSON (with sorted keys) into w.
+func (c *Client) ExportDcSettings(dcName string, w io.Writer, opts DcSettingsOpts) error {
+	settings, err := c.GetDcSettings(dcName)
+	if err != nil {
+		return err
+	}
+	opts = opts.forDc(dcName)
+	export := make(map[string]interface{})
+	v := reflect.ValueOf(*settings)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if name := dcSettingName(t.Field(i)); name != "" && opts.includes(t.Field(i)) {
+			export[name] = v.Field(i).Interface()
+		}
+	}
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return errors.Newf(err, "failed to export settings of virtual datacenter \"%s\"", dcName)
+	}
+	if _, err = w.Write(append(data, '\n')); err != nil {
+		return errors.Newf(err, "failed to export settings of virtual datacenter \"%s\"", dcName)
+	}
+	return nil
+}
+
+// ImportDcSettings reads settings in JSON format (as written by ExportDcSettings) from r
+// and applies them to a virtual datacenter. The input may contain only a subset of settings;
+// settings not present in the input or not selected by opts are left unchanged.
+func (c *Client) ImportDcSettings(dcName string, r io.Reader, opts DcSettingsOpts) (*DcSettings, error) {
+	var raw map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, errors.NewInvalidArgumentf(err, "", "failed to parse settings for virtual datacenter \"%s\"", dcName)
+	}
+	current, err := c.GetDcSettings(dcName)
+	if err != nil {
+		return nil, err
+	}
+	// decode over the current settings so that missing keys keep their values
+	desired := *current
+	for k, v := range raw {
+		if err := setDcSetting(&desired, k, v); err != nil {
+			return nil, err
+		}
+	}
+	return c.SetDcSettings(dcName, desired, opts)
+}
+
+// Helper to get the API name of a DcSettings struct field.
+func dcSettingName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// Helper that tells whether a value is a nil or empty slice or map.
+func isEmptyCollection(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return false
+}
+
+// Helper to verify that all keys are known settings.
+func checkDcSettingsKeys(changes DcSettingsChanges) error {
+	known := make(map[string]bool)
+	t := reflect.TypeOf(DcSettings{})
+	for i := 0; i < t.NumField(); i++ {
+		if name := dcSettingName(t.Field(i)); name != "" {
+			known[name] = true
+		}
+	}
+	for k := range changes {
+		if !known[k] {
+			return errors.NewInvalidArgumentf(nil, "", "unknown vDC setting %s", k)
+		}
+	}
+	return nil
+}
+
+// Helper to decode a single JSON value into the matching DcSettings field.
+func setDcSetting(s *DcSettings, name string, value json.RawMessage) error {
+	v := reflect.ValueOf(s).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if dcSettingName(t.Field(i)) == name {
+			field := reflect.New(t.Field(i).Type)
+			if err := json.Unmarshal(value, field.Interface()); err != nil {
+				return errors.NewInvalidArgumentf(err, "", "invalid value for vDC setting %s", name)
+			}
+			v.Field(i).Set(field.Elem())
+			return nil
+		}
+	}
+	return errors.NewInvalidArgumentf(nil, "", "unknown vDC setting %s", name)
+}
@@ -0,0 +1,385 @@
+package cloudapi
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/erigones/godanube/client"
+	"github.com/erigones/godanube/errors"
+)
+
+const (
+	// DNS domain types
+	DnsDomainMaster = "MASTER"
+	DnsDomainNative = "NATIVE"
+
+	// supported DNS record types
+	DnsRecordA     = "A"
+	DnsRecordAAAA  = "AAAA"
+	DnsRecordCNAME = "CNAME"
+	DnsRecordMX    = "MX"
+	DnsRecordTXT   = "TXT"
+	DnsRecordPTR   = "PTR"
+	DnsRecordSRV   = "SRV"
+	DnsRecordNS    = "NS"
+)
+
+// DnsDomain represents a DNS domain (zone) served by the Danube Cloud PowerDNS server
+// https://docs.danubecloud.org/api-reference/api/dns_domain.html
+type DnsDomain struct {
+	ReqData
+	Name     string   `json:"name,omitempty"`
+	Type     string   `json:"type,omitempty"` // DnsDomainMaster or DnsDomainNative
+	Owner    string   `json:"owner,omitempty"`
+	Access   int      `json:"access,omitempty"`
+	Desc     string   `json:"desc,omitempty"`
+	DcBound  *bool    `json:"dc_bound,omitempty"`  // whether the domain is dedicated to one vDC (nil: unchanged, see Bool())
+	TsigKeys []string `json:"tsig_keys,omitempty"` // TSIG keys for zone transfers ("algorithm:name:secret")
+
+	// Not settable, only for querying:
+	Records int      `json:"records,omitempty"` // number of records in the domain
+	Dcs     []string `json:"dcs,omitempty"`     // vDC list where the domain is attached
+}
+
+// DnsRecord represents a resource record in a DNS domain
+// https://docs.danubecloud.org/api-reference/api/dns_domain_record.html
+type DnsRecord struct {
+	ReqData
+	Id       int    `json:"id,omitempty"`       // record ID assigned by the server
+	Domain   string `json:"domain,omitempty"`   // domain the record belongs to
+	Name     string `json:"name,omitempty"`     // fully qualified record name
+	Type     string `json:"type,omitempty"`     // one of DnsRecord*
+	Content  string `json:"content,omitempty"`  // e.g. IP address for A records
+	Ttl      int    `json:"ttl,omitempty"`      // time to live in seconds
+	Prio     int    `json:"prio,omitempty"`     // priority of MX and SRV records
+	Disabled *bool  `json:"disabled,omitempty"` // disabled records are not served (nil: unchanged, see Bool())
+	Changed  string `json:"changed,omitempty"`  // last change time (only for querying)
+}
+
+/*** STRUCTS FOR DNS-SPECIFIC DC RESPONSES ***/
+type DnsDomainResponse struct {
+	DcResponse
+	Result DnsDomain `json:"result"`
+}
+
+type DnsDomainResponseFull struct {
+	DcResponse
+	Result []DnsDomain `json:"result"`
+}
+
+type DnsRecordResponse struct {
+	DcResponse
+	Result DnsRecord `json:"result"`
+}
+
+type DnsRecordResponseFull struct {
+	DcResponse
+	Result []DnsRecord `json:"result"`
+}
+
+// ListDnsDomains returns names of DNS domains visible to the caller.
+func (c *Client) ListDnsDomains() ([]string, error) {
+	var resp ResponseList
+	req := request{
+		method: client.GET,
+		url:    makeURL("dns", "domain"),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of DNS domains")
+	}
+	return resp.Result, nil
+}
+
+// ListDnsDomainsFull returns details of DNS domains visible to the caller.
+func (c *Client) ListDnsDomainsFull() ([]DnsDomain, error) {
+	var resp DnsDomainResponseFull
+	filter := NewFilter()
+	filter.Set("full", "true")
+	req := request{
+		method: client.GET,
+		url:    makeURL("dns", "domain"),
+		filter: filter,
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of DNS domains")
+	}
+	return resp.Result, nil
+}
+
+// GetDnsDomain returns details of a DNS domain.
+func (c *Client) GetDnsDomain(domainName string) (*DnsDomain, error) {
+	var resp DnsDomainResponse
+	req := request{
+		method: client.GET,
+		url:    makeURL("dns", "domain", domainName),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get DNS domain \"%s\"", domainName)
+	}
+	return &resp.Result, nil
+}
+
+// CreateDnsDomain creates a new DNS domain.
+func (c *Client) CreateDnsDomain(opts DnsDomain) (*DnsDomain, error) {
+	var resp DnsDomainResponse
+	req := request{
+		method:           client.POST,
+		url:              makeURL("dns", "domain", opts.Name),
+		reqValue:         &opts,
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to create DNS domain \"%s\"", opts.Name)
+	}
+	return &resp.Result, nil
+}
+
+// UpdateDnsDomain changes a DNS domain. Empty (nil) fields are left unchanged.
+func (c *Client) UpdateDnsDomain(opts DnsDomain) (*DnsDomain, error) {
+	var resp DnsDomainResponse
+	req := request{
+		method:   client.PUT,
+		url:      makeURL("dns", "domain", opts.Name),
+		reqValue: &opts,
+		resp:     &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to update DNS domain \"%s\"", opts.Name)
+	}
+	return &resp.Result, nil
+}
+
+// DeleteDnsDomain deletes a DNS domain together with all its records.
+func (c *Client) DeleteDnsDomain(domainName string) error {
+	var resp DcResponse
+	req := request{
+		method: client.DELETE,
+		url:    makeURL("dns", "domain", domainName),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return errors.Newf2(err, resp.Detail, "failed to delete DNS domain \"%s\"", domainName)
+	}
+	return nil
+}
+
+// ListDnsRecords returns all records of a DNS domain.
+func (c *Client) ListDnsRecords(domainName string) ([]DnsRecord, error) {
+	var resp DnsRecordResponseFull
+	filter := NewFilter()
+	filter.Set("full", "true")
+	req := request{
+		method: client.GET,
+		url:    makeURL("dns", "domain", domainName, "record"),
+		filter: filter,
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of records in DNS domain \"%s\"", domainName)
+	}
+	for i := range resp.Result {
+		if resp.Result[i].Domain == "" {
+			resp.Result[i].Domain = domainName
+		}
+	}
+	return resp.Result, nil
+}
+
+// GetDnsRecord returns a record of a DNS domain.
+func (c *Client) GetDnsRecord(domainName string, recordId int) (*DnsRecord, error) {
+	var resp DnsRecordResponse
+	req := request{
+		method: client.GET,
+		url:    makeURL("dns", "domain", domainName, "record", strconv.Itoa(recordId)),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get record %d in DNS domain \"%s\"", recordId, domainName)
+	}
+	return &resp.Result, nil
+}
+
+// CreateDnsRecord adds a new record to the DNS domain opts.Domain.
+// The record is checked to be consistent with its type before it is sent.
+func (c *Client) CreateDnsRecord(opts DnsRecord) (*DnsRecord, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	var resp DnsRecordResponse
+	req := request{
+		method:           client.POST,
+		url:              makeURL("dns", "domain", opts.Domain, "record"),
+		reqValue:         &opts,
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to create %s record \"%s\" in DNS domain \"%s\"", opts.Type, opts.Name, opts.Domain)
+	}
+	return &resp.Result, nil
+}
+
+// UpdateDnsRecord changes the record opts.Id in the DNS domain opts.Domain.
+// Empty (nil) fields are left unchanged, e.g. Disabled: Bool(false) enables the record again.
+// A changed type or content is checked together with the rest of the record before it is sent.
+func (c *Client) UpdateDnsRecord(opts DnsRecord) (*DnsRecord, error) {
+	if err := c.validateDnsRecordUpdate(opts); err != nil {
+		return nil, err
+	}
+	var resp DnsRecordResponse
+	req := request{
+		method:   client.PUT,
+		url:      makeURL("dns", "domain", opts.Domain, "record", strconv.Itoa(opts.Id)),
+		reqValue: &opts,
+		resp:     &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to update record %d in DNS domain \"%s\"", opts.Id, opts.Domain)
+	}
+	return &resp.Result, nil
+}
+
+// DeleteDnsRecord deletes a record from a DNS domain.
+func (c *Client) DeleteDnsRecord(domainName string, recordId int) error {
+	var resp DcResponse
+	req := request{
+		method: client.DELETE,
+		url:    makeURL("dns", "domain", domainName, "record", strconv.Itoa(recordId)),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return errors.Newf2(err, resp.Detail, "failed to delete record %d in DNS domain \"%s\"", recordId, domainName)
+	}
+	return nil
+}
+
+// ListMachineDnsRecords returns records in all DNS domains visible to the caller
+// that reference IP addresses of the machine: A and AAAA records pointing to the IPs,
+// PTR records of the IPs and CNAME records pointing to names of the found A/AAAA records.
+func (c *Client) ListMachineDnsRecords(machineID string) ([]DnsRecord, error) {
+	nics, err := c.GetMachineNics(machineID)
+	if err != nil {
+		return nil, err
+	}
+	ips := make(map[string]bool)
+	ptrNames := make(map[string]bool)
+	for _, nic := range nics {
+		if ip := net.ParseIP(nic.Ip); ip != nil {
+			ips[ip.String()] = true
+			ptrNames[reverseDnsName(ip)] = true
+		}
+	}
+	if len(ips) == 0 {
+		return nil, nil
+	}
+
+	domains, err := c.ListDnsDomains()
+	if err != nil {
+		return nil, err
+	}
+	var all []DnsRecord
+	for _, domain := range domains {
+		records, err := c.ListDnsRecords(domain)
+		if err != nil {
+			return nil, errors.Newf(err, "failed to get DNS records of machine \"%s\"", machineID)
+		}
+		all = append(all, records...)
+	}
+
+	var found []DnsRecord
+	hostnames := make(map[string]bool)
+	for _, r := range all {
+		switch r.Type {
+		case DnsRecordA, DnsRecordAAAA:
+			if ip := net.ParseIP(r.Content); ip != nil && ips[ip.String()] {
+				found = append(found, r)
+				hostnames[strings.ToLower(strings.TrimSuffix(r.Name, "."))] = true
+			}
+		case DnsRecordPTR:
+			if ptrNames[strings.ToLower(strings.TrimSuffix(r.Name, "."))] {
+				found = append(found, r)
+			}
+		}
+	}
+	for _, r := range all {
+		if r.Type == DnsRecordCNAME && hostnames[strings.ToLower(strings.TrimSuffix(r.Content, "."))] {
+			found = append(found, r)
+		}
+	}
+	return found, nil
+}
+
+// Validate checks that the record content matches the record type.
+func (r *DnsRecord) Validate() error {
+	var problem string
+	switch r.Type {
+	case DnsRecordA:
+		if ip := net.ParseIP(r.Content); ip == nil || ip.To4() == nil {
+			problem = "content must be an IPv4 address"
+		}
+	case DnsRecordAAAA:
+		if ip := net.ParseIP(r.Content); ip == nil || ip.To4() != nil {
+			problem = "content must be an IPv6 address"
+		}
+	case DnsRecordCNAME, DnsRecordPTR, DnsRecordNS, DnsRecordMX:
+		if r.Content == "" || net.ParseIP(r.Content) != nil {
+			problem = "content must be a host name"
+		}
+	case DnsRecordSRV:
+		// PowerDNS format: "<weight> <port> <target>", priority is in Prio
+		if fields := strings.Fields(r.Content); len(fields) != 3 {
+			problem = "content must be in format \"<weight> <port> <target>\""
+		}
+	case DnsRecordTXT:
+	default:
+		problem = fmt.Sprintf("unsupported record type \"%s\"", r.Type)
+	}
+	if problem != "" {
+		return errors.NewInvalidArgumentf(nil, "", "invalid %s record \"%s\": %s", r.Type, r.Name, problem)
+	}
+	return nil
+}
+
+// Helper that checks the type and content of a record after the update opts.
+// The current record is fetched if only one of them is changed.
+func (c *Client) validateDnsRecordUpdate(opts DnsRecord) error {
+	if opts.Type == "" && opts.Content == "" {
+		return nil
+	}
+	record := opts
+	if opts.Type == "" || opts.Content == "" {
+		current, err := c.GetDnsRecord(opts.Domain, opts.Id)
+		if err != nil {
+			return err
+		}
+		record = *current
+		if opts.Type != "" {
+			record.Type = opts.Type
+		}
+		if opts.Content != "" {
+			record.Content = opts.Content
+		}
+	}
+	return record.Validate()
+}
+
+// Helper that returns the name of the PTR record of an IP address
+// (e.g. "4.3.2.1.in-addr.arpa" for 1.2.3.4).
+func reverseDnsName(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa", ip4[3], ip4[2], ip4[1], ip4[0])
+	}
+	const hexDigits = "0123456789abcdef"
+	nibbles := make([]string, 0, 2*net.IPv6len)
+	for i := net.IPv6len - 1; i >= 0; i-- {
+		nibbles = append(nibbles, string(hexDigits[ip[i]&0xf]), string(hexDigits[ip[i]>>4]))
+	}
+	return strings.Join(nibbles, ".") + ".ip6.arpa"
+}
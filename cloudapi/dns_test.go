@@ -0,0 +1,76 @@
+package cloudapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/erigones/godanube/auth"
+	"github.com/erigones/godanube/client"
+)
+
+// Helper that returns a client talking to a test server with the handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	a, err := auth.NewAuth("user", "", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := &auth.Credentials{UserAuthentication: a, ApiEndpoint: auth.Endpoint{URL: srv.URL}, VirtDatacenter: "main"}
+	return New(client.NewClient(creds, "", log.New(ioutil.Discard, "", 0)))
+}
+
+func TestUpdateDnsRecordDisabledOnly(t *testing.T) {
+	for _, disabled := range []bool{true, false} {
+		var requests []string
+		var body map[string]interface{}
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			if r.Method == http.MethodPut {
+				json.NewDecoder(r.Body).Decode(&body)
+			}
+			json.NewEncoder(w).Encode(DnsRecordResponse{DcResponse: DcResponse{Status: "SUCCESS"}, Result: DnsRecord{Id: 7, Disabled: Bool(disabled)}})
+		})
+
+		record, err := c.UpdateDnsRecord(DnsRecord{Domain: "example.com", Id: 7, Disabled: Bool(disabled)})
+		if err != nil {
+			t.Fatalf("disabled %v: %v", disabled, err)
+		}
+		if len(requests) != 1 || !strings.HasPrefix(requests[0], "PUT ") || !strings.HasSuffix(strings.TrimSuffix(requests[0], "/"), "/dns/domain/example.com/record/7") {
+			t.Errorf("disabled %v: unexpected requests %v", disabled, requests)
+		}
+		_, hasType := body["type"]
+		_, hasContent := body["content"]
+		if body["disabled"] != disabled || hasType || hasContent {
+			t.Errorf("disabled %v: unexpected request body %v", disabled, body)
+		}
+		if record.Disabled == nil || *record.Disabled != disabled {
+			t.Errorf("disabled %v: unexpected result %+v", disabled, record)
+		}
+	}
+}
+
+func TestUpdateDnsRecordContentOnly(t *testing.T) {
+	var puts int
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			puts++
+		}
+		json.NewEncoder(w).Encode(DnsRecordResponse{DcResponse: DcResponse{Status: "SUCCESS"}, Result: DnsRecord{Id: 7, Type: DnsRecordA, Content: "192.0.2.1"}})
+	})
+
+	if _, err := c.UpdateDnsRecord(DnsRecord{Domain: "example.com", Id: 7, Content: "192.0.2.2"}); err != nil {
+		t.Errorf("valid content rejected: %v", err)
+	}
+	if _, err := c.UpdateDnsRecord(DnsRecord{Domain: "example.com", Id: 7, Content: "2001:db8::1"}); err == nil {
+		t.Errorf("IPv6 content accepted for an A record")
+	}
+	if puts != 1 {
+		t.Errorf("got %d updates, want 1", puts)
+	}
+}
@@ -2,181 +2,186 @@ package cloudapi
 
 import (
 	"net/http"
-	"strconv"
 
 	"github.com/erigones/godanube/client"
 	"github.com/erigones/godanube/errors"
 )
 
-type FabricVLAN struct {
-	Id          int16  `json:"vlan_id"`               // Number between 0-4095 indicating VLAN Id
-	Name        string `json:"name"`                  // Unique name to identify VLAN
-	Description string `json:"description,omitempty"` // Optional description of the VLAN
+const (
+	// ARP failure policies of an overlay rule
+	OverlayArpFailureDrop   = "drop"
+	OverlayArpFailureIgnore = "ignore"
+
+	// nic_tag_type of networks built on top of an overlay rule
+	NicTagTypeOverlay = "overlay_rule"
+
+	DefaultOverlayPort = 4789 // IANA VXLAN port
+	MaxVxlanId         = 16777215
+)
+
+// OverlayRule represents a VXLAN overlay definition on a compute node.
+// Networks with nic_tag set to the rule name are transported over the overlay.
+// https://docs.danubecloud.org/api-reference/api/node_overlay.html
+type OverlayRule struct {
+	ReqData
+	Name             string `json:"name,omitempty"`
+	Node             string `json:"node,omitempty"`               // compute node hostname
+	Port             int    `json:"port,omitempty"`               // UDP port of the VXLAN listener (default 4789)
+	Ip               string `json:"ip,omitempty"`                 // listen address (default is the node admin IP)
+	ArpFailurePolicy string `json:"arp_failure_policy,omitempty"` // one of OverlayArpFailure*
+}
+
+// OverlayNetworkOpts represent the option that can be specified
+// when creating a network on top of an overlay rule.
+type OverlayNetworkOpts struct {
+	Network            // network name, addressing, resolvers, etc.
+	OverlayRule string // name of the overlay rule (nic_tag)
+	VxlanId     int    // VXLAN segment ID (1 - 16777215)
 }
 
-type FabricNetwork struct {
-	Id               string            `json:"id"`                  // Unique identifier for network
-	Name             string            `json:"name"`                // Network name
-	Public           bool              `json:"public"`              // Whether or not this is an RFC1918 network
-	Fabric           bool              `json:"fabric"`              // Whether this network is on a fabric
-	Description      string            `json:"description"`         // Optional description of network
-	Subnet           string            `json:"subnet"`              // CIDR formatted string describing network
-	ProvisionStartIp string            `json:"provision_start_ip"`  // First IP on the network that can be assigned
-	ProvisionEndIp   string            `json:"provision_end_ip"`    // Last assignable IP on the network
-	Gateway          string            `json:"gateway"`             // Optional Gateway IP
-	Resolvers        []string          `json:"resolvers,omitempty"` // Array of IP addresses for resolvers
-	Routes           map[string]string `json:"routes,omitempty"`    // Map of CIDR block to Gateway IP Address
-	InternetNAT      bool              `json:"internet_nat"`        // If a NAT zone is provisioned at Gateway IP Address
-	VLANId           int16             `json:"vlan_id"`             // VLAN network is on
+/*** STRUCTS FOR OVERLAY-SPECIFIC DC RESPONSES ***/
+type OverlayRuleResponse struct {
+	DcResponse
+	Result OverlayRule `json:"result"`
 }
 
-type CreateFabricNetworkOpts struct {
-	Name             string            `json:"name"`                  // Network name
-	Description      string            `json:"description,omitempty"` // Optional description of network
-	Subnet           string            `json:"subnet"`                // CIDR formatted string describing network
-	ProvisionStartIp string            `json:"provision_start_ip"`    // First IP on the network that can be assigned
-	ProvisionEndIp   string            `json:"provision_end_ip"`      // Last assignable IP on the network
-	Gateway          string            `json:"gateway,omitempty"`     // Optional Gateway IP
-	Resolvers        []string          `json:"resolvers,omitempty"`   // Array of IP addresses for resolvers
-	Routes           map[string]string `json:"routes,omitempty"`      // Map of CIDR block to Gateway IP Address
-	InternetNAT      bool              `json:"internet_nat"`          // If a NAT zone is provisioned at Gateway IP Address
+type OverlayRuleResponseFull struct {
+	DcResponse
+	Result []OverlayRule `json:"result"`
 }
 
-// ListFabricVLANs lists VLANs
-// See API docs: https://apidocs.joyent.com/cloudapi/#ListFabricVLANs
-func (c *Client) ListFabricVLANs() ([]FabricVLAN, error) {
-	var resp []FabricVLAN
+// ListOverlayRules lists overlay rules defined on a compute node.
+func (c *Client) ListOverlayRules(nodeHostname string) ([]OverlayRule, error) {
+	var resp OverlayRuleResponseFull
+	filter := NewFilter()
+	filter.Set("full", "true")
 	req := request{
 		method: client.GET,
-		url:    apiFabricVLANs,
+		url:    makeURL("node", nodeHostname, "overlay"),
+		filter: filter,
 		resp:   &resp,
 	}
 	if _, err := c.sendRequest(req); err != nil {
-		return nil, errors.Newf(err, "failed to get list of fabric VLANs")
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of overlay rules on node \"%s\"", nodeHostname)
 	}
-	return resp, nil
+	return resp.Result, nil
 }
 
-// GetFabricLAN retrieves a single VLAN by ID
-// See API docs: https://apidocs.joyent.com/cloudapi/#GetFabricVLAN
-func (c *Client) GetFabricVLAN(vlanID int16) (*FabricVLAN, error) {
-	var resp FabricVLAN
+// GetOverlayRule retrieves a single overlay rule of a compute node.
+func (c *Client) GetOverlayRule(nodeHostname, ruleName string) (*OverlayRule, error) {
+	var resp OverlayRuleResponse
 	req := request{
 		method: client.GET,
-		url:    makeURL(apiFabricVLANs, strconv.Itoa(int(vlanID))),
+		url:    makeURL("node", nodeHostname, "overlay", ruleName),
 		resp:   &resp,
 	}
 	if _, err := c.sendRequest(req); err != nil {
-		return nil, errors.Newf(err, "failed to get fabric VLAN with id %d", vlanID)
+		return nil, errors.Newf2(err, resp.Detail, "failed to get overlay rule \"%s\" on node \"%s\"", ruleName, nodeHostname)
 	}
-	return &resp, nil
+	return &resp.Result, nil
 }
 
-// CreateFabricVLAN creates a new VLAN with the specified options
-// See API docs: https://apidocs.joyent.com/cloudapi/#CreateFabricVLAN
-func (c *Client) CreateFabricVLAN(vlan FabricVLAN) (*FabricVLAN, error) {
-	var resp FabricVLAN
+// CreateOverlayRule creates a new overlay rule on the compute node opts.Node.
+func (c *Client) CreateOverlayRule(opts OverlayRule) (*OverlayRule, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	var resp OverlayRuleResponse
 	req := request{
-		method:         client.POST,
-		url:            apiFabricVLANs,
-		reqValue:       vlan,
-		resp:           &resp,
-		expectedStatus: http.StatusCreated,
+		method:           client.POST,
+		url:              makeURL("node", opts.Node, "overlay", opts.Name),
+		reqValue:         &opts,
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
 	}
 	if _, err := c.sendRequest(req); err != nil {
-		return nil, errors.Newf(err, "failed to create fabric VLAN: %d - %s", vlan.Id, vlan.Name)
+		return nil, errors.Newf2(err, resp.Detail, "failed to create overlay rule \"%s\" on node \"%s\"", opts.Name, opts.Node)
 	}
-	return &resp, nil
+	return &resp.Result, nil
 }
 
-// UpdateFabricVLAN updates a given VLAN with new fields
-// See API docs: https://apidocs.joyent.com/cloudapi/#UpdateFabricVLAN
-func (c *Client) UpdateFabricVLAN(vlan FabricVLAN) (*FabricVLAN, error) {
-	var resp FabricVLAN
+// UpdateOverlayRule changes an overlay rule on the compute node opts.Node.
+func (c *Client) UpdateOverlayRule(opts OverlayRule) (*OverlayRule, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	var resp OverlayRuleResponse
 	req := request{
-		method:         client.PUT,
-		url:            makeURL(apiFabricVLANs, strconv.Itoa(int(vlan.Id))),
-		reqValue:       vlan,
-		resp:           &resp,
-		expectedStatus: http.StatusAccepted,
+		method:   client.PUT,
+		url:      makeURL("node", opts.Node, "overlay", opts.Name),
+		reqValue: &opts,
+		resp:     &resp,
 	}
 	if _, err := c.sendRequest(req); err != nil {
-		return nil, errors.Newf(err, "failed to update fabric VLAN with id %d to %s - %s", vlan.Id, vlan.Name, vlan.Description)
+		return nil, errors.Newf2(err, resp.Detail, "failed to update overlay rule \"%s\" on node \"%s\"", opts.Name, opts.Node)
 	}
-	return &resp, nil
+	return &resp.Result, nil
 }
 
-// DeleteFabricVLAN delets a given VLAN as specified by ID
-// See API docs: https://apidocs.joyent.com/cloudapi/#DeleteFabricVLAN
-func (c *Client) DeleteFabricVLAN(vlanID int16) error {
+// DeleteOverlayRule deletes an overlay rule from a compute node.
+// Networks using the rule must be deleted first.
+func (c *Client) DeleteOverlayRule(nodeHostname, ruleName string) error {
+	var resp DcResponse
 	req := request{
-		method:         client.DELETE,
-		url:            makeURL(apiFabricVLANs, strconv.Itoa(int(vlanID))),
-		expectedStatus: http.StatusNoContent,
+		method: client.DELETE,
+		url:    makeURL("node", nodeHostname, "overlay", ruleName),
+		resp:   &resp,
 	}
 	if _, err := c.sendRequest(req); err != nil {
-		return errors.Newf(err, "failed to delete fabric VLAN with id %d", vlanID)
+		return errors.Newf2(err, resp.Detail, "failed to delete overlay rule \"%s\" on node \"%s\"", ruleName, nodeHostname)
 	}
 	return nil
 }
 
-// ListFabricNetworks lists the networks inside the given VLAN
-// See API docs: https://apidocs.joyent.com/cloudapi/#ListFabricNetworks
-func (c *Client) ListFabricNetworks(vlanID int16) ([]FabricNetwork, error) {
-	var resp []FabricNetwork
-	req := request{
-		method: client.GET,
-		url:    makeURL(apiFabricVLANs, strconv.Itoa(int(vlanID)), apiFabricNetworks),
-		resp:   &resp,
-	}
-	if _, err := c.sendRequest(req); err != nil {
-		return nil, errors.Newf(err, "failed to get list of networks on fabric %d", vlanID)
-	}
-	return resp, nil
+// CreateOverlayNetwork creates a network transported over an overlay rule.
+// The overlay rule must exist on all nodes where VMs using the network will run.
+func (c *Client) CreateOverlayNetwork(opts OverlayNetworkOpts) (*Network, error) {
+	if opts.OverlayRule == "" {
+		return nil, errors.NewMissingParameterf(nil, "", "overlay rule for network \"%s\" is not set", opts.Name)
+	}
+	if opts.VxlanId < 1 || opts.VxlanId > MaxVxlanId {
+		return nil, errors.NewInvalidArgumentf(nil, "", "VXLAN ID %d of network \"%s\" is out of range 1-%d", opts.VxlanId, opts.Name, MaxVxlanId)
+	}
+	network := opts.Network
+	network.Nic_tag = opts.OverlayRule
+	network.Nic_tag_type = NicTagTypeOverlay
+	network.Vxlan_id = opts.VxlanId
+	return c.CreateNetwork(network)
 }
 
-// GetFabricNetwork gets a single network by VLAN and Network IDs
-// See API docs: https://apidocs.joyent.com/cloudapi/#GetFabricNetwork
-func (c *Client) GetFabricNetwork(vlanID int16, networkID string) (*FabricNetwork, error) {
-	var resp FabricNetwork
+// ListOverlayNetworks returns networks transported over the overlay rule ruleName
+// (all overlay networks if ruleName is empty). This call needs SuperAdmin rights.
+func (c *Client) ListOverlayNetworks(ruleName string) ([]Network, error) {
+	var resp NetworkResponseFull
+	filter := NewFilter()
+	filter.Set("full", "true")
 	req := request{
 		method: client.GET,
-		url:    makeURL(apiFabricVLANs, strconv.Itoa(int(vlanID)), apiFabricNetworks, networkID),
+		url:    "network",
+		filter: filter,
 		resp:   &resp,
 	}
 	if _, err := c.sendRequest(req); err != nil {
-		return nil, errors.Newf(err, "failed to get fabric network %s on vlan %d", networkID, vlanID)
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of overlay networks")
 	}
-	return &resp, nil
-}
-
-// CreateFabricNetwork creates a new fabric network
-// See API docs: https://apidocs.joyent.com/cloudapi/#CreateFabricNetwork
-func (c *Client) CreateFabricNetwork(vlanID int16, opts CreateFabricNetworkOpts) (*FabricNetwork, error) {
-	var resp FabricNetwork
-	req := request{
-		method:         client.POST,
-		url:            makeURL(apiFabricVLANs, strconv.Itoa(int(vlanID)), apiFabricNetworks),
-		reqValue:       opts,
-		resp:           &resp,
-		expectedStatus: http.StatusCreated,
+	var networks []Network
+	for _, n := range resp.Result {
+		if n.Nic_tag_type == NicTagTypeOverlay && (ruleName == "" || n.Nic_tag == ruleName) {
+			networks = append(networks, n)
+		}
 	}
-	if _, err := c.sendRequest(req); err != nil {
-		return nil, errors.Newf(err, "failed to create fabric network %s on vlan %d", opts.Name, vlanID)
-	}
-	return &resp, nil
+	return networks, nil
 }
 
-// DeleteFabricNetwork deletes an existing fabric network
-// See API docs: https://apidocs.joyent.com/cloudapi/#DeleteFabricNetwork
-func (c *Client) DeleteFabricNetwork(vlanID int16, networkID string) error {
-	req := request{
-		method:         client.DELETE,
-		url:            makeURL(apiFabricVLANs, strconv.Itoa(int(vlanID)), apiFabricNetworks, networkID),
-		expectedStatus: http.StatusNoContent,
-	}
-	if _, err := c.sendRequest(req); err != nil {
-		return errors.Newf(err, "failed to delete fabric network %s on vlan %d", networkID, vlanID)
+// Helper to check overlay rule values before sending them to the API.
+func (r *OverlayRule) validate() error {
+	if r.Port < 0 || r.Port > 65535 {
+		return errors.NewInvalidArgumentf(nil, "", "invalid port %d of overlay rule \"%s\"", r.Port, r.Name)
+	}
+	switch r.ArpFailurePolicy {
+	case "", OverlayArpFailureDrop, OverlayArpFailureIgnore:
+	default:
+		return errors.NewInvalidArgumentf(nil, "", "invalid ARP failure policy \"%s\" of overlay rule \"%s\"", r.ArpFailurePolicy, r.Name)
 	}
 	return nil
 }
@@ -129,6 +129,7 @@ func (c *Client) DeleteFirewallRule(fwRuleID string) error {
 }
 
 // ListFirewallRuleMachines return the list of machines affected by the given firewall rule.
+// The firewall API is not ported to Danube yet, so the machines are in the Joyent format (Machine).
 // See API docs: http://apidocs.joyent.com/cloudapi/#ListFirewallRuleMachines
 func (c *Client) ListFirewallRuleMachines(fwRuleID string) ([]Machine, error) {
 	var resp []Machine
@@ -0,0 +1,168 @@
+package cloudapi
+
+import (
+	"net/http"
+
+	"github.com/erigones/godanube/client"
+	"github.com/erigones/godanube/errors"
+)
+
+// Group represents a user group (role) with a set of permissions
+// https://docs.danubecloud.org/api-reference/api/accounts_group.html
+type Group struct {
+	ReqData
+	Name        string   `json:"name,omitempty"`
+	Alias       string   `json:"alias,omitempty"`
+	Permissions []string `json:"permissions,omitempty"` // permission names granted to the group
+	Users       []string `json:"users,omitempty"`       // usernames of group members
+	DcBound     *bool    `json:"dc_bound,omitempty"`    // whether the group is dedicated to one vDC (nil: unchanged, see Bool())
+
+	// Not settable, only for querying:
+	Dcs []string `json:"dcs,omitempty"` // vDC list where the group is attached
+}
+
+// Permission represents a single permission that can be granted to a group
+type Permission struct {
+	Name  string `json:"name,omitempty"`
+	Alias string `json:"alias,omitempty"`
+}
+
+/*** STRUCTS FOR GROUP-SPECIFIC DC RESPONSES ***/
+type GroupResponse struct {
+	DcResponse
+	Result Group `json:"result"`
+}
+
+type GroupResponseFull struct {
+	DcResponse
+	Result []Group `json:"result"`
+}
+
+type PermissionResponseFull struct {
+	DcResponse
+	Result []Permission `json:"result"`
+}
+
+// ListGroups returns names of all user groups visible to the caller.
+func (c *Client) ListGroups() ([]string, error) {
+	var resp ResponseList
+	req := request{
+		method: client.GET,
+		url:    makeURL("accounts", "group"),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of groups")
+	}
+	return resp.Result, nil
+}
+
+// ListGroupsFull returns details of all user groups visible to the caller.
+func (c *Client) ListGroupsFull() ([]Group, error) {
+	var resp GroupResponseFull
+	filter := NewFilter()
+	filter.Set("full", "true")
+	req := request{
+		method: client.GET,
+		url:    makeURL("accounts", "group"),
+		filter: filter,
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of groups")
+	}
+	return resp.Result, nil
+}
+
+// GetGroup returns details of a user group.
+func (c *Client) GetGroup(groupName string) (*Group, error) {
+	var resp GroupResponse
+	req := request{
+		method: client.GET,
+		url:    makeURL("accounts", "group", groupName),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get group \"%s\"", groupName)
+	}
+	return &resp.Result, nil
+}
+
+// CreateGroup creates a new user group.
+func (c *Client) CreateGroup(opts Group) (*Group, error) {
+	var resp GroupResponse
+	req := request{
+		method:           client.POST,
+		url:              makeURL("accounts", "group", opts.Name),
+		reqValue:         &opts,
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to create group \"%s\"", opts.Name)
+	}
+	return &resp.Result, nil
+}
+
+// UpdateGroup changes an existing user group. Permissions and Users replace the current lists
+// when set; empty (nil) fields are left unchanged.
+func (c *Client) UpdateGroup(opts Group) (*Group, error) {
+	var resp GroupResponse
+	req := request{
+		method:   client.PUT,
+		url:      makeURL("accounts", "group", opts.Name),
+		reqValue: &opts,
+		resp:     &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to update group \"%s\"", opts.Name)
+	}
+	return &resp.Result, nil
+}
+
+// DeleteGroup deletes a user group.
+func (c *Client) DeleteGroup(groupName string) error {
+	var resp DcResponse
+	req := request{
+		method: client.DELETE,
+		url:    makeURL("accounts", "group", groupName),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return errors.Newf2(err, resp.Detail, "failed to delete group \"%s\"", groupName)
+	}
+	return nil
+}
+
+// ListPermissions returns all permissions that can be granted to a group.
+func (c *Client) ListPermissions() ([]Permission, error) {
+	var resp PermissionResponseFull
+	filter := NewFilter()
+	filter.Set("full", "true")
+	req := request{
+		method: client.GET,
+		url:    makeURL("accounts", "permission"),
+		filter: filter,
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of permissions")
+	}
+	return resp.Result, nil
+}
+
+// ListDatacenterGroups returns names of user groups attached to a virtual datacenter.
+func (c *Client) ListDatacenterGroups(dcName string) ([]string, error) {
+	return c.listAttachedToDatacenter(dcName, "group")
+}
+
+// AttachGroup attaches a user group to a virtual datacenter,
+// which gives the group members access to the vDC.
+func (c *Client) AttachGroup(dcName, groupName string) error {
+	return c.attachToDatacenter(dcName, "group", groupName, nil)
+}
+
+// DetachGroup detaches a user group from a virtual datacenter.
+func (c *Client) DetachGroup(dcName, groupName string) error {
+	return c.detachFromDatacenter(dcName, "group", groupName)
+}
@@ -0,0 +1,210 @@
+package cloudapi
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/erigones/godanube/client"
+	"github.com/erigones/godanube/errors"
+)
+
+// ImportImageFromUrlOpts represent the option that can be specified
+// when importing an image from an arbitrary manifest URL.
+// https://docs.danubecloud.org/api-reference/api/image_base.html#post--image-(name)
+type ImportImageFromUrlOpts struct {
+	ReqData
+	Name        string   `json:"-"`                  // name of the new image
+	ManifestUrl string   `json:"manifest_url"`       // URL of the image manifest
+	FileUrl     string   `json:"file_url,omitempty"` // URL of the image file (default is derived from manifest_url)
+	Alias       string   `json:"alias,omitempty"`
+	Version     string   `json:"version,omitempty"` // overrides the version from the manifest
+	Desc        string   `json:"desc,omitempty"`
+	Access      int      `json:"access,omitempty"`
+	Owner       string   `json:"owner,omitempty"`
+	DcBound     bool     `json:"dc_bound,omitempty"`
+	Resize      bool     `json:"resize,omitempty"`
+	Deploy      bool     `json:"deploy,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// UploadImageOpts represent the option that can be specified
+// when uploading an image from a local file.
+type UploadImageOpts struct {
+	ReqData
+	Name     string          `json:"-"`        // name of the new image
+	Manifest json.RawMessage `json:"manifest"` // image manifest, filled in by UploadImage()
+	Alias    string          `json:"alias,omitempty"`
+	Version  string          `json:"version,omitempty"`
+	Desc     string          `json:"desc,omitempty"`
+	Access   int             `json:"access,omitempty"`
+	Owner    string          `json:"owner,omitempty"`
+	DcBound  bool            `json:"dc_bound,omitempty"`
+	Resize   bool            `json:"resize,omitempty"`
+	Deploy   bool            `json:"deploy,omitempty"`
+	Tags     []string        `json:"tags,omitempty"`
+}
+
+// ProgressFunc is called repeatedly during an upload with the number of bytes sent so far
+// and the total number of bytes.
+type ProgressFunc func(sent, total int64)
+
+// ImportImageFromUrl imports an image from a manifest URL (e.g. an image exported
+// from another Danube Cloud installation) and waits until the image is ready.
+func (c *Client) ImportImageFromUrl(opts ImportImageFromUrlOpts) (*Image, error) {
+	if opts.ManifestUrl == "" {
+		return nil, errors.NewMissingParameterf(nil, "", "manifest URL of image \"%s\" is not set", opts.Name)
+	}
+	var resp DcResponse
+	req := request{
+		method:           client.POST,
+		url:              makeURL("image", opts.Name),
+		reqValue:         &opts,
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to import image \"%s\" from %s", opts.Name, opts.ManifestUrl)
+	}
+
+	if err := c.waitForOptionalTask(&resp, imageImportTimeout, req.expectedStatuses); err != nil {
+		return nil, errors.Newf(err, "failed to import image \"%s\" from %s", opts.Name, opts.ManifestUrl)
+	}
+
+	return c.GetImage(opts.Name)
+}
+
+// UploadImage creates a new image from a local image file and its manifest.
+// The file size and SHA1 checksum are verified against the manifest before anything is sent.
+// The image is registered first and then the file is streamed to the server;
+// progress (if not nil) is called as the data is sent.
+// If the upload fails, the incomplete image is deleted.
+func (c *Client) UploadImage(opts UploadImageOpts, manifestPath, filePath string, progress ProgressFunc) (*Image, error) {
+	errMsg := "failed to upload image \"%s\""
+	manifest, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return nil, errors.Newf(err, errMsg, opts.Name)
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, errors.Newf(err, errMsg, opts.Name)
+	}
+	defer file.Close()
+
+	size, err := verifyImageFile(manifest, file)
+	if err != nil {
+		return nil, errors.NewInvalidArgumentf(err, "", errMsg, opts.Name)
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return nil, errors.Newf(err, errMsg, opts.Name)
+	}
+
+	opts.Manifest = json.RawMessage(manifest)
+	var resp DcResponse
+	req := request{
+		method:           client.POST,
+		url:              makeURL("image", opts.Name),
+		reqValue:         &opts,
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, errMsg, opts.Name)
+	}
+	if err := c.waitForOptionalTask(&resp, imageUpdateTimeout, req.expectedStatuses); err != nil {
+		return nil, errors.Newf(err, errMsg, opts.Name)
+	}
+
+	var body io.Reader = file
+	if progress != nil {
+		body = &progressReader{r: file, total: size, progress: progress}
+	}
+	var uploadResp DcResponse
+	uploadReq := request{
+		method:           client.PUT,
+		url:              makeURL("image", opts.Name, "upload"),
+		reqReader:        body,
+		reqLength:        size,
+		resp:             &uploadResp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+	}
+	if opts.Dc != "" {
+		uploadReq.filter = NewFilter()
+		uploadReq.filter.Set("dc", opts.Dc)
+	}
+	if _, err = c.sendBinaryRequest(uploadReq); err == nil {
+		err = c.waitForOptionalTask(&uploadResp, imageImportTimeout, uploadReq.expectedStatuses)
+	} else {
+		err = errors.Newf2(err, uploadResp.Detail, "upload of %s failed", filePath)
+	}
+	if err != nil {
+		c.DeleteImage(opts.Name) // nothing more to do if the cleanup fails
+		return nil, errors.Newf(err, errMsg, opts.Name)
+	}
+
+	return c.GetImage(opts.Name)
+}
+
+// VerifyImageFile checks the size and SHA1 checksum of an image file
+// against the first file entry of its manifest.
+func VerifyImageFile(manifestPath, filePath string) error {
+	manifest, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = verifyImageFile(manifest, file)
+	return err
+}
+
+// Helper that reads the whole image file and compares it with the manifest.
+// Returns the file size.
+func verifyImageFile(manifest []byte, file io.Reader) (int64, error) {
+	m, err := ParseImageManifest(manifest)
+	if err != nil {
+		return 0, err
+	}
+	if len(m.Files) == 0 || m.Files[0].Sha1 == "" {
+		return 0, fmt.Errorf("image manifest contains no file checksum")
+	}
+
+	hash := sha1.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, err
+	}
+	if m.Files[0].Size != 0 && m.Files[0].Size != size {
+		return 0, fmt.Errorf("image file size %d does not match manifest size %d", size, m.Files[0].Size)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(sum, m.Files[0].Sha1) {
+		return 0, fmt.Errorf("image file SHA1 checksum %s does not match manifest checksum %s", sum, m.Files[0].Sha1)
+	}
+	return size, nil
+}
+
+// progressReader reports the number of bytes read to a ProgressFunc.
+type progressReader struct {
+	r        io.Reader
+	sent     int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.sent += int64(n)
+		p.progress(p.sent, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,171 @@
+package cloudapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/erigones/godanube/errors"
+)
+
+const (
+	// image types used in manifests
+	ImageTypeZoneDataset = "zone-dataset"
+	ImageTypeLxDataset   = "lx-dataset"
+	ImageTypeZvol        = "zvol"
+	ImageTypeDocker      = "docker"
+	ImageTypeOther       = "other"
+
+	// operating systems used in manifests
+	ImageOsSmartOS = "smartos"
+	ImageOsIllumos = "illumos"
+	ImageOsLinux   = "linux"
+	ImageOsBSD     = "bsd"
+	ImageOsWindows = "windows"
+	ImageOsOther   = "other"
+)
+
+// ImageManifest represents an image manifest as stored in image repositories
+// (SmartOS IMGAPI manifest format).
+type ImageManifest struct {
+	Uuid         string                 `json:"uuid"`
+	Name         string                 `json:"name"`
+	Version      string                 `json:"version"`
+	Description  string                 `json:"description,omitempty"`
+	Homepage     string                 `json:"homepage,omitempty"`
+	Owner        string                 `json:"owner,omitempty"`
+	Os           string                 `json:"os"`   // one of ImageOs*
+	Type         string                 `json:"type"` // one of ImageType*
+	State        string                 `json:"state,omitempty"`
+	Public       bool                   `json:"public,omitempty"`
+	PublishedAt  string                 `json:"published_at,omitempty"`
+	ImageSize    int                    `json:"image_size,omitempty"` // zvol size in MB
+	Files        []ImageFile            `json:"files"`
+	Requirements ImageRequirements      `json:"requirements,omitempty"`
+	Tags         map[string]interface{} `json:"tags,omitempty"`
+}
+
+// ImageFile describes a file (usually only one) of the image
+type ImageFile struct {
+	Sha1        string `json:"sha1"`
+	Size        int64  `json:"size"` // in bytes
+	Compression string `json:"compression,omitempty"`
+}
+
+// ImageRequirements lists constraints on machines created from the image
+type ImageRequirements struct {
+	MinRam      int               `json:"min_ram,omitempty"` // in MB
+	MaxRam      int               `json:"max_ram,omitempty"` // in MB
+	Brand       string            `json:"brand,omitempty"`   // zone brand, e.g. "kvm", "bhyve", "joyent", "lx"
+	SshKey      bool              `json:"ssh_key,omitempty"`
+	MinPlatform map[string]string `json:"min_platform,omitempty"`
+	MaxPlatform map[string]string `json:"max_platform,omitempty"`
+	Bootrom     string            `json:"bootrom,omitempty"`
+}
+
+// ParseImageManifest decodes an image manifest from JSON.
+func ParseImageManifest(data []byte) (*ImageManifest, error) {
+	var m ImageManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid image manifest: %v", err)
+	}
+	return &m, nil
+}
+
+// OsType returns the Danube ostype (OsType* constant) of machines created from the image,
+// or 0 if it cannot be determined.
+func (m *ImageManifest) OsType() int {
+	switch m.Type {
+	case ImageTypeZoneDataset:
+		return OsTypeSunosZone
+	case ImageTypeLxDataset, ImageTypeDocker:
+		return OsTypeLinuxZone
+	case ImageTypeZvol:
+		switch m.Os {
+		case ImageOsLinux:
+			return OsTypeLinux
+		case ImageOsSmartOS, ImageOsIllumos:
+			return OsTypeSunOs
+		case ImageOsBSD:
+			return OsTypeBSD
+		case ImageOsWindows:
+			return OsTypeWindows
+		}
+	}
+	return 0
+}
+
+// IsZvol returns true if the image is a disk image for hardware virtualized machines.
+func (m *ImageManifest) IsZvol() bool {
+	return m.Type == ImageTypeZvol
+}
+
+// CheckCompatibility tells whether the image can be deployed into the machine definition.
+// Ostype, RAM and the size of the first disk are checked; unset (zero) values in the
+// definition mean Danube defaults and are not checked.
+func (m *ImageManifest) CheckCompatibility(opts *CreateMachineOpts) error {
+	var problems []string
+	if ostype := m.OsType(); ostype != 0 && opts.Vm.OsType != 0 && ostype != opts.Vm.OsType {
+		problems = append(problems, fmt.Sprintf("image ostype %d differs from machine ostype %d", ostype, opts.Vm.OsType))
+	}
+	if ram := opts.Vm.Ram; ram != 0 {
+		if m.Requirements.MinRam != 0 && ram < m.Requirements.MinRam {
+			problems = append(problems, fmt.Sprintf("image requires at least %d MB of RAM, machine has %d MB", m.Requirements.MinRam, ram))
+		}
+		if m.Requirements.MaxRam != 0 && ram > m.Requirements.MaxRam {
+			problems = append(problems, fmt.Sprintf("image allows at most %d MB of RAM, machine has %d MB", m.Requirements.MaxRam, ram))
+		}
+	}
+	if m.IsZvol() && m.ImageSize != 0 && len(opts.Disks) > 0 && opts.Disks[0].Size != 0 && opts.Disks[0].Size < m.ImageSize {
+		problems = append(problems, fmt.Sprintf("image needs a disk of at least %d MB, first disk has %d MB", m.ImageSize, opts.Disks[0].Size))
+	}
+	return incompatibilityError(m.Name, opts.Vm.Name, problems)
+}
+
+// CheckCompatibility tells whether the image can be deployed into the machine definition.
+// The image manifest is used if available (remote images), otherwise the image ostype
+// and size are checked.
+func (img *Image) CheckCompatibility(opts *CreateMachineOpts) error {
+	if img.Manifest != nil {
+		return img.Manifest.CheckCompatibility(opts)
+	}
+	var problems []string
+	if img.Ostype != 0 && opts.Vm.OsType != 0 && img.Ostype != opts.Vm.OsType {
+		problems = append(problems, fmt.Sprintf("image ostype %d differs from machine ostype %d", img.Ostype, opts.Vm.OsType))
+	}
+	if img.Size != 0 && len(opts.Disks) > 0 && opts.Disks[0].Size != 0 && opts.Disks[0].Size < img.Size {
+		problems = append(problems, fmt.Sprintf("image needs a disk of at least %d MB, first disk has %d MB", img.Size, opts.Disks[0].Size))
+	}
+	return incompatibilityError(img.Name, opts.Vm.Name, problems)
+}
+
+// CheckRemoteImageCompatibility fetches the manifest of an image in an image repository
+// and checks it against the machine definition (e.g. before ImportImage() is called).
+func (c *Client) CheckRemoteImageCompatibility(imageUuid, repoName string, opts *CreateMachineOpts) error {
+	img, err := c.GetRemoteImageInfo(imageUuid, repoName)
+	if err != nil {
+		return err
+	}
+	if img.Manifest == nil {
+		return errors.Newf(nil, "image \"%s\" in repository \"%s\" has no manifest", imageUuid, repoName)
+	}
+	return img.Manifest.CheckCompatibility(opts)
+}
+
+// CheckImageCompatibility checks an image attached to the current vDC against
+// the machine definition (e.g. before CreateMachine() is called).
+func (c *Client) CheckImageCompatibility(imageName string, opts *CreateMachineOpts) error {
+	img, err := c.GetAttachedImage(imageName)
+	if err != nil {
+		return err
+	}
+	return img.CheckCompatibility(opts)
+}
+
+// Helper that joins the found problems into one error.
+func incompatibilityError(imageName, vmName string, problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.NewInvalidArgumentf(nil, "", "image \"%s\" is not compatible with machine \"%s\": %s", imageName, vmName, strings.Join(problems, "; "))
+}
@@ -9,6 +9,8 @@ import (
 )
 
 const (
+	imageCreateTimeout = 1800 / TaskQuerySleepTime
+	imageUpdateTimeout = 120 / TaskQuerySleepTime
 	imageDeleteTimeout = 120
 	imageImportTimeout = 1800
 )
@@ -30,7 +32,7 @@ type Image struct {
 	DcBound bool `json:"dc_bound,omitempty"` // Whether the image is dedicated to one vDC
 
 	/* only for GetRemoteImageInfo() */
-	Manifest	interface{}	`json:"manifest,omitempty"`
+	Manifest *ImageManifest `json:"manifest,omitempty"`
 
 	/*DELME
 	Requirements map[string]interface{} // Minimum requirements for provisioning a machine with this image, e.g. 'password' indicates that a password must be provided
@@ -82,16 +84,59 @@ type MantaLocation struct {
 }*/
 
 // CreateImageFromMachineOpts represent the option that can be specified
-// when creating a new image from an existing machine.
+// when creating a new image from a snapshot of an existing machine.
+// https://docs.danubecloud.org/api-reference/api/vm_snapshot.html
 type CreateImageFromMachineOpts struct {
-	Machine     string            `json:"machine"`               // The machine UUID from which the image is to be created
-	Name        string            `json:"name"`                  // Image name
-	Version     string            `json:"version"`               // Image version
-	Description string            `json:"description,omitempty"` // Image description
-	Homepage    string            `json:"homepage,omitempty"`    // URL for a web page including detailed information for this image
-	EULA        string            `json:"eula,omitempty"`        // URL of the End User License Agreement (EULA) for the image
-	ACL         []string          `json:"acl,omitempty"`         // An array of account UUIDs given access to a private image. The field is only relevant to private images
-	Tags        map[string]string `json:"tags,omitempty"`        // A map of key/value pairs that allows clients to categorize images by any given criteria
+	ReqData
+	Machine  string   `json:"-"`                  // hostname or UUID of the source machine
+	Snapshot string   `json:"-"`                  // name of the machine snapshot the image is created from
+	Name     string   `json:"-"`                  // name of the new image
+	DiskId   int      `json:"disk_id,omitempty"`  // machine disk the snapshot belongs to (default 1)
+	Alias    string   `json:"alias,omitempty"`    // image alias (default is the name)
+	Version  string   `json:"version,omitempty"`  // image version
+	Desc     string   `json:"desc,omitempty"`     // image description
+	Access   int      `json:"access,omitempty"`   // AccessPublic or AccessPrivate
+	Owner    string   `json:"owner,omitempty"`    // owner username
+	DcBound  bool     `json:"dc_bound,omitempty"` // dedicate the image to the current vDC
+	Resize   bool     `json:"resize,omitempty"`   // resize the root disk at deploy
+	Deploy   bool     `json:"deploy,omitempty"`   // image is a deploy-once image
+	Tags     []string `json:"tags,omitempty"`     // image tags
+}
+
+// UpdateImageOpts represent the image attributes that can be changed.
+// Flags, tags and the description are always sent (so that they can be cleared), the other
+// attributes only when set, so start from Image.UpdateOpts() and change only the attributes
+// that should be different.
+type UpdateImageOpts struct {
+	ReqData
+	Name    string   `json:"-"` // name of the image to update
+	Alias   string   `json:"alias,omitempty"`
+	Version string   `json:"version,omitempty"`
+	Desc    string   `json:"desc"`
+	Access  int      `json:"access,omitempty"`
+	Owner   string   `json:"owner,omitempty"`
+	DcBound bool     `json:"dc_bound"`
+	Resize  bool     `json:"resize"`
+	Deploy  bool     `json:"deploy"`
+	Tags    []string `json:"tags"`
+}
+
+// UpdateOpts returns update options initialized with the current image attributes.
+func (img *Image) UpdateOpts() UpdateImageOpts {
+	tags := make([]string, len(img.Tags))
+	copy(tags, img.Tags)
+	return UpdateImageOpts{
+		Name:    img.Name,
+		Alias:   img.Alias,
+		Version: img.Version,
+		Desc:    img.Desc,
+		Access:  img.Access,
+		Owner:   img.Owner,
+		DcBound: img.DcBound,
+		Resize:  img.Resize,
+		Deploy:  img.Deploy,
+		Tags:    tags,
+	}
 }
 
 // ListImages provides a list of image names available in the Danube Cloud.
@@ -203,22 +248,51 @@ func (c *Client) DeleteImage(imageName string) error {
 	return nil
 }
 
-/*
+// CreateImageFromMachine creates a new image from a machine snapshot and waits until the image is ready.
+// The machine must be stopped or the snapshot must be consistent on its own (e.g. created with FsFreeze).
 func (c *Client) CreateImageFromMachine(opts CreateImageFromMachineOpts) (*Image, error) {
-	var resp Image
+	var resp DcResponse
+	req := request{
+		method:           client.POST,
+		url:              makeURL("vm", opts.Machine, "snapshot", opts.Snapshot, "image", opts.Name),
+		reqValue:         &opts,
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to create image \"%s\" from snapshot \"%s\" of machine \"%s\"", opts.Name, opts.Snapshot, opts.Machine)
+	}
+
+	if err := c.waitForOptionalTask(&resp, imageCreateTimeout, req.expectedStatuses); err != nil {
+		return nil, errors.Newf(err, "failed to create image \"%s\" from snapshot \"%s\" of machine \"%s\"", opts.Name, opts.Snapshot, opts.Machine)
+	}
+
+	return c.GetImage(opts.Name)
+}
+
+// UpdateImage changes image attributes and waits until the change is propagated to compute nodes.
+func (c *Client) UpdateImage(opts UpdateImageOpts) (*Image, error) {
+	if opts.Tags == nil {
+		opts.Tags = []string{}
+	}
+	var resp DcResponse
 	req := request{
-		method:         client.POST,
-		url:            apiImages,
-		reqValue:       opts,
-		resp:           &resp,
-		expectedStatus: http.StatusCreated,
+		method:           client.PUT,
+		url:              makeURL("image", opts.Name),
+		reqValue:         &opts,
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
 	}
 	if _, err := c.sendRequest(req); err != nil {
-		return nil, errors.Newf(err, "failed to create image from machine %s", opts.Machine)
+		return nil, errors.Newf2(err, resp.Detail, "failed to update image \"%s\"", opts.Name)
 	}
-	return &resp, nil
+
+	if err := c.waitForOptionalTask(&resp, imageUpdateTimeout, req.expectedStatuses); err != nil {
+		return nil, errors.Newf(err, "failed to update image \"%s\"", opts.Name)
+	}
+
+	return c.GetImage(opts.Name)
 }
-*/
 
 func (c *Client) ListImgRepos() ([]string, error) {
 	//J
@@ -0,0 +1,238 @@
+package cloudapi
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erigones/godanube/client"
+	"github.com/erigones/godanube/errors"
+)
+
+// image repositories are a global setting, which can be changed only in the main vDC
+const mainVirtDC = "main"
+
+// RemoteImage is an image available in an image repository (imagestore)
+type RemoteImage struct {
+	ImageManifest
+	Repo string `json:"-"` // name of the repository the image was found in
+}
+
+// ImageSearchOpts represent the filters that can be specified when searching
+// for images in image repositories. Empty values match everything.
+type ImageSearchOpts struct {
+	Repos           []string  // repositories to search in (default is all configured repositories)
+	Name            string    // image name; shell patterns are allowed (e.g. "ubuntu-*")
+	Os              string    // one of ImageOs*
+	Type            string    // one of ImageType*
+	MinVersion      string    // lowest acceptable version (inclusive)
+	MaxVersion      string    // highest acceptable version (inclusive)
+	PublishedAfter  time.Time // only images published at or after this time
+	PublishedBefore time.Time // only images published before this time
+}
+
+/*** STRUCTS FOR IMAGESTORE-SPECIFIC DC RESPONSES ***/
+type RemoteImageResponseFull struct {
+	DcResponse
+	Result []RemoteImage `json:"result"`
+}
+
+// AddImgRepo adds a new image repository. This call needs SuperAdmin rights.
+func (c *Client) AddImgRepo(repoName, url string) error {
+	return c.modifyImgRepos(func(repos map[string]string) error {
+		if _, exists := repos[repoName]; exists {
+			return errors.NewAlreadyExistsf(nil, "", "image repository \"%s\" already exists", repoName)
+		}
+		repos[repoName] = url
+		return nil
+	})
+}
+
+// UpdateImgRepo changes the URL of an image repository. This call needs SuperAdmin rights.
+func (c *Client) UpdateImgRepo(repoName, url string) error {
+	return c.modifyImgRepos(func(repos map[string]string) error {
+		if _, exists := repos[repoName]; !exists {
+			return errors.NewResourceNotFoundf(nil, "", "image repository \"%s\" does not exist", repoName)
+		}
+		repos[repoName] = url
+		return nil
+	})
+}
+
+// DeleteImgRepo removes an image repository. This call needs SuperAdmin rights.
+func (c *Client) DeleteImgRepo(repoName string) error {
+	return c.modifyImgRepos(func(repos map[string]string) error {
+		if _, exists := repos[repoName]; !exists {
+			return errors.NewResourceNotFoundf(nil, "", "image repository \"%s\" does not exist", repoName)
+		}
+		delete(repos, repoName)
+		return nil
+	})
+}
+
+// ListRemoteImagesFull returns details of all images in an image repository.
+func (c *Client) ListRemoteImagesFull(repoName string) ([]RemoteImage, error) {
+	var resp RemoteImageResponseFull
+	filter := NewFilter()
+	filter.Set("full", "true")
+	req := request{
+		method: client.GET,
+		url:    makeURL("imagestore", repoName, "image"),
+		filter: filter,
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of images in repository \"%s\"", repoName)
+	}
+	for i := range resp.Result {
+		resp.Result[i].Repo = repoName
+	}
+	return resp.Result, nil
+}
+
+// SearchRemoteImages returns images from image repositories matching opts,
+// sorted by name and from the newest version.
+func (c *Client) SearchRemoteImages(opts ImageSearchOpts) ([]RemoteImage, error) {
+	repos := opts.Repos
+	if len(repos) == 0 {
+		var err error
+		if repos, err = c.ListImgRepos(); err != nil {
+			return nil, err
+		}
+	}
+
+	var found []RemoteImage
+	for _, repo := range repos {
+		images, err := c.ListRemoteImagesFull(repo)
+		if err != nil {
+			return nil, err
+		}
+		for _, img := range images {
+			if opts.matches(&img) {
+				found = append(found, img)
+			}
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].Name != found[j].Name {
+			return found[i].Name < found[j].Name
+		}
+		return found[j].olderThan(&found[i])
+	})
+	return found, nil
+}
+
+// FindLatestRemoteImage returns the newest version of the image imageName
+// (shell patterns are allowed) matching the other filters in opts.
+func (c *Client) FindLatestRemoteImage(imageName string, opts ImageSearchOpts) (*RemoteImage, error) {
+	opts.Name = imageName
+	images, err := c.SearchRemoteImages(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, errors.NewResourceNotFoundf(nil, "", "no image \"%s\" found in image repositories", imageName)
+	}
+	latest := &images[0]
+	for i := 1; i < len(images); i++ {
+		if latest.olderThan(&images[i]) {
+			latest = &images[i]
+		}
+	}
+	return latest, nil
+}
+
+// CompareImageVersions compares two image versions part by part ("18.04.1" < "18.04.10").
+// Numeric parts are compared as numbers, other parts as strings.
+// The result is 0 if a == b, -1 if a < b, and +1 if a > b.
+func CompareImageVersions(a, b string) int {
+	split := func(v string) []string {
+		return strings.FieldsFunc(v, func(r rune) bool { return r == '.' || r == '-' || r == '_' })
+	}
+	pa, pb := split(a), split(b)
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		switch {
+		case errA == nil && errB == nil && na != nb:
+			if na < nb {
+				return -1
+			}
+			return 1
+		case (errA != nil || errB != nil) && pa[i] != pb[i]:
+			if pa[i] < pb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(pa) < len(pb):
+		return -1
+	case len(pa) > len(pb):
+		return 1
+	}
+	return 0
+}
+
+// Helper that applies a change to the image repositories setting.
+func (c *Client) modifyImgRepos(change func(repos map[string]string) error) error {
+	settings, err := c.GetDcSettings(mainVirtDC)
+	if err != nil {
+		return err
+	}
+	repos := make(map[string]string, len(settings.VmsImageRepositories))
+	for name, url := range settings.VmsImageRepositories {
+		repos[name] = url
+	}
+	if err := change(repos); err != nil {
+		return err
+	}
+	_, err = c.UpdateDcSettings(mainVirtDC, DcSettingsChanges{"VMS_IMAGE_REPOSITORIES": repos})
+	return err
+}
+
+// Helper that checks a remote image against the search filters.
+func (opts *ImageSearchOpts) matches(img *RemoteImage) bool {
+	if opts.Name != "" {
+		if ok, err := path.Match(opts.Name, img.Name); err != nil || !ok {
+			return false
+		}
+	}
+	if opts.Os != "" && opts.Os != img.Os {
+		return false
+	}
+	if opts.Type != "" && opts.Type != img.Type {
+		return false
+	}
+	if opts.MinVersion != "" && CompareImageVersions(img.Version, opts.MinVersion) < 0 {
+		return false
+	}
+	if opts.MaxVersion != "" && CompareImageVersions(img.Version, opts.MaxVersion) > 0 {
+		return false
+	}
+	if !opts.PublishedAfter.IsZero() || !opts.PublishedBefore.IsZero() {
+		published, err := time.Parse(time.RFC3339, img.PublishedAt)
+		if err != nil {
+			return false
+		}
+		if !opts.PublishedAfter.IsZero() && published.Before(opts.PublishedAfter) {
+			return false
+		}
+		if !opts.PublishedBefore.IsZero() && !published.Before(opts.PublishedBefore) {
+			return false
+		}
+	}
+	return true
+}
+
+// Helper that orders images by version and then by publish date.
+func (img *RemoteImage) olderThan(other *RemoteImage) bool {
+	if cmp := CompareImageVersions(img.Version, other.Version); cmp != 0 {
+		return cmp < 0
+	}
+	return img.PublishedAt < other.PublishedAt
+}
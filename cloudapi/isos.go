@@ -0,0 +1,121 @@
+package cloudapi
+
+import (
+	"net/http"
+
+	"github.com/erigones/godanube/client"
+	"github.com/erigones/godanube/errors"
+)
+
+// Iso represents an ISO image that can be inserted into the CD-ROM drive of a KVM machine
+// https://docs.danubecloud.org/api-reference/api/iso.html
+type Iso struct {
+	ReqData
+	GenericDcEntity          // contains name, alias, owner, access, desc, etc.
+	OsType          int      `json:"ostype,omitempty"`   // the ISO is meant for this ostype
+	DcBound         bool     `json:"dc_bound,omitempty"` // whether the ISO is dedicated to one vDC
+	Dcs             []string `json:"dcs,omitempty"`      // vDC list where the ISO is attached (only for querying)
+}
+
+/*** STRUCTS FOR ISO-SPECIFIC DC RESPONSES ***/
+type IsoResponse struct {
+	DcResponse
+	Result Iso `json:"result"`
+}
+
+type IsoResponseFull struct {
+	DcResponse
+	Result []Iso `json:"result"`
+}
+
+// ListIsos returns names of all ISO images. This call needs SuperAdmin rights.
+// With Admin rights use ListDatacenterIsos().
+func (c *Client) ListIsos() ([]string, error) {
+	var resp ResponseList
+	req := request{
+		method: client.GET,
+		url:    "iso",
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of ISO images")
+	}
+	return resp.Result, nil
+}
+
+// ListIsosFull returns details of all ISO images. This call needs SuperAdmin rights.
+func (c *Client) ListIsosFull() ([]Iso, error) {
+	var resp IsoResponseFull
+	filter := NewFilter()
+	filter.Set("full", "true")
+	req := request{
+		method: client.GET,
+		url:    "iso",
+		filter: filter,
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of ISO images")
+	}
+	return resp.Result, nil
+}
+
+// GetIso returns details of an ISO image.
+func (c *Client) GetIso(isoName string) (*Iso, error) {
+	var resp IsoResponse
+	req := request{
+		method: client.GET,
+		url:    makeURL("iso", isoName),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get ISO image \"%s\"", isoName)
+	}
+	return &resp.Result, nil
+}
+
+// CreateIso registers a new ISO image. The ISO file itself has to be present
+// on the compute nodes (in the ISO directory) under the same name.
+func (c *Client) CreateIso(opts Iso) (*Iso, error) {
+	var resp IsoResponse
+	req := request{
+		method:           client.POST,
+		url:              makeURL("iso", opts.Name),
+		reqValue:         &opts,
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to create ISO image \"%s\"", opts.Name)
+	}
+	return &resp.Result, nil
+}
+
+// UpdateIso changes an ISO image.
+func (c *Client) UpdateIso(opts Iso) (*Iso, error) {
+	var resp IsoResponse
+	req := request{
+		method:   client.PUT,
+		url:      makeURL("iso", opts.Name),
+		reqValue: &opts,
+		resp:     &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to update ISO image \"%s\"", opts.Name)
+	}
+	return &resp.Result, nil
+}
+
+// DeleteIso deletes an ISO image.
+func (c *Client) DeleteIso(isoName string) error {
+	var resp DcResponse
+	req := request{
+		method: client.DELETE,
+		url:    makeURL("iso", isoName),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return errors.Newf2(err, resp.Detail, "failed to delete ISO image \"%s\"", isoName)
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package cloudapi
+
+// Iterators walk list endpoints lazily: nothing is requested until the first call
+// of Next(), the next page is requested only when the current one is used up and
+// Close() (or leaving a range loop) stops fetching. Usage:
+//
+//	it := c.IterMachines(NewMachineQuery().Tags("web"))
+//	for it.Next() {
+//		vm := it.Value()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// With Go 1.23 or newer, All() can be used in a range loop:
+//
+//	for vm := range it.All() {
+//		...
+//	}
+//	err := it.Err()
+//
+// Machines are requested from the server page by page when the query allows it
+// (see MachineQuery); otherwise, and for the other lists, which Danube returns
+// in one response, the whole list is fetched as a single page on the first Next().
+
+// DefaultIterPageSize is the number of machines requested at once by IterMachines()
+const DefaultIterPageSize = 100
+
+// pager fetches the page number page (counted from 1), stores its items in the
+// iterator and returns their count and whether more pages follow
+type pager func(page int) (count int, more bool, err error)
+
+// listIterator holds the paging state shared by the typed iterators
+type listIterator struct {
+	fetch  pager
+	page   int  // last fetched page
+	count  int  // items in the current page
+	pos    int  // index of the current item in the page
+	more   bool // more pages follow the current one
+	closed bool
+	err    error
+}
+
+func newListIterator(fetch pager) listIterator {
+	return listIterator{fetch: fetch, pos: -1}
+}
+
+// next advances to the next item, fetching the next page if needed.
+func (it *listIterator) next() bool {
+	for it.pos+1 >= it.count {
+		if it.closed || it.err != nil || (it.page > 0 && !it.more) {
+			return false
+		}
+		it.page++
+		it.count, it.more, it.err = it.fetch(it.page)
+		it.pos = -1
+		if it.err != nil {
+			it.count = 0
+			return false
+		}
+	}
+	it.pos++
+	return true
+}
+
+// valid tells whether the iterator is at an item (Next() returned true).
+func (it *listIterator) valid() bool {
+	return it.pos >= 0 && it.pos < it.count
+}
+
+// Close stops the iteration; no more pages are fetched.
+func (it *listIterator) Close() {
+	it.closed = true
+	it.count = 0
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *listIterator) Err() error {
+	return it.err
+}
+
+// MachineIterator iterates over machine details
+type MachineIterator struct {
+	listIterator
+	items []VmDetails
+}
+
+// IterMachines returns an iterator over machines matching the query (see QueryMachines()).
+// Pages of DefaultIterPageSize machines are requested from the server when the query
+// has no Offset() or Limit() and the server can do all its filtering and ordering.
+// A server ignoring the paging parameters is detected by a page with more machines
+// than requested or by a page repeating machines already returned.
+func (c *Client) IterMachines(q *MachineQuery) *MachineIterator {
+	if q == nil {
+		q = NewMachineQuery()
+	}
+	it := &MachineIterator{}
+	seen := make(map[string]bool)
+	it.listIterator = newListIterator(func(page int) (int, bool, error) {
+		pq := q.page(page, DefaultIterPageSize)
+		if q.offset != 0 || q.limit != 0 || !pq.serverPaged() {
+			pq = q
+		}
+		vms, err := c.fetchMachines(pq)
+		if err != nil {
+			it.items = nil
+			return 0, false, err
+		}
+		if pq == q {
+			// the whole list was requested at once
+			it.items = q.apply(vms)
+			return len(it.items), false, nil
+		}
+
+		var fresh []VmDetails
+		for _, vm := range vms {
+			if !seen[vm.Uuid] {
+				seen[vm.Uuid] = true
+				fresh = append(fresh, vm)
+			}
+		}
+		it.items = q.apply(fresh)
+		more := len(vms) == pq.limit && len(fresh) > 0
+		return len(it.items), more, nil
+	})
+	return it
+}
+
+// Next advances to the next machine and returns false when there are no more machines.
+func (it *MachineIterator) Next() bool {
+	return it.next()
+}
+
+// Value returns the current machine (zero value if Next() was not called or returned false).
+func (it *MachineIterator) Value() VmDetails {
+	if !it.valid() {
+		return VmDetails{}
+	}
+	return it.items[it.pos]
+}
+
+// All returns the remaining machines as a range-over-func sequence.
+func (it *MachineIterator) All() func(yield func(VmDetails) bool) {
+	return func(yield func(VmDetails) bool) {
+		for it.Next() {
+			if !yield(it.Value()) {
+				it.Close()
+				return
+			}
+		}
+	}
+}
+
+// ImageIterator iterates over images attached to the current vDC
+type ImageIterator struct {
+	listIterator
+	items []Image
+}
+
+// IterAttachedImages returns an iterator over images attached to the current vDC (see ListAttachedImages()).
+// The list is fetched as a single page.
+func (c *Client) IterAttachedImages() *ImageIterator {
+	it := &ImageIterator{}
+	it.listIterator = newListIterator(func(page int) (int, bool, error) {
+		var err error
+		it.items, err = c.ListAttachedImages()
+		return len(it.items), false, err
+	})
+	return it
+}
+
+// Next advances to the next image and returns false when there are no more images.
+func (it *ImageIterator) Next() bool {
+	return it.next()
+}
+
+// Value returns the current image (zero value if Next() was not called or returned false).
+func (it *ImageIterator) Value() Image {
+	if !it.valid() {
+		return Image{}
+	}
+	return it.items[it.pos]
+}
+
+// All returns the remaining images as a range-over-func sequence.
+func (it *ImageIterator) All() func(yield func(Image) bool) {
+	return func(yield func(Image) bool) {
+		for it.Next() {
+			if !yield(it.Value()) {
+				it.Close()
+				return
+			}
+		}
+	}
+}
+
+// NetworkIterator iterates over networks attached to the current vDC
+type NetworkIterator struct {
+	listIterator
+	items []Network
+}
+
+// IterAttachedNetworks returns an iterator over networks attached to the current vDC (see GetAttachedNetworks()).
+// The list is fetched as a single page.
+func (c *Client) IterAttachedNetworks() *NetworkIterator {
+	it := &NetworkIterator{}
+	it.listIterator = newListIterator(func(page int) (int, bool, error) {
+		var err error
+		it.items, err = c.GetAttachedNetworks()
+		return len(it.items), false, err
+	})
+	return it
+}
+
+// Next advances to the next network and returns false when there are no more networks.
+func (it *NetworkIterator) Next() bool {
+	return it.next()
+}
+
+// Value returns the current network (zero value if Next() was not called or returned false).
+func (it *NetworkIterator) Value() Network {
+	if !it.valid() {
+		return Network{}
+	}
+	return it.items[it.pos]
+}
+
+// All returns the remaining networks as a range-over-func sequence.
+func (it *NetworkIterator) All() func(yield func(Network) bool) {
+	return func(yield func(Network) bool) {
+		for it.Next() {
+			if !yield(it.Value()) {
+				it.Close()
+				return
+			}
+		}
+	}
+}
+
+// TaskIterator iterates over IDs of running tasks
+type TaskIterator struct {
+	listIterator
+	items []string
+}
+
+// IterRunningTasks returns an iterator over IDs of running tasks (see GetRunningTasks()).
+// The list is fetched as a single page.
+// Use GetTaskInfo() to get details of the tasks that are of interest.
+func (c *Client) IterRunningTasks() *TaskIterator {
+	it := &TaskIterator{}
+	it.listIterator = newListIterator(func(page int) (int, bool, error) {
+		var err error
+		it.items, err = c.GetRunningTasks()
+		return len(it.items), false, err
+	})
+	return it
+}
+
+// Next advances to the next task and returns false when there are no more tasks.
+func (it *TaskIterator) Next() bool {
+	return it.next()
+}
+
+// Value returns the ID of the current task (empty if Next() was not called or returned false).
+func (it *TaskIterator) Value() string {
+	if !it.valid() {
+		return ""
+	}
+	return it.items[it.pos]
+}
+
+// All returns the remaining task IDs as a range-over-func sequence.
+func (it *TaskIterator) All() func(yield func(string) bool) {
+	return func(yield func(string) bool) {
+		for it.Next() {
+			if !yield(it.Value()) {
+				it.Close()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package cloudapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestIterMachinesPaging(t *testing.T) {
+	tests := []struct {
+		name     string
+		machines int
+		honor    bool // the server pages the list
+		requests int
+	}{
+		{"paged", DefaultIterPageSize + 50, true, 2},
+		{"paged, full last page", DefaultIterPageSize, true, 2},
+		{"ignored", DefaultIterPageSize + 50, false, 1},
+		{"ignored, exactly one page", DefaultIterPageSize, false, 2},
+	}
+	for _, test := range tests {
+		var vms []VmDetails
+		for i := 0; i < test.machines; i++ {
+			vms = append(vms, VmDetails{Hostname: fmt.Sprintf("vm%03d", i), Uuid: fmt.Sprintf("u%d", i)})
+		}
+		requests := 0
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			res := vms
+			if page, _ := strconv.Atoi(r.URL.Query().Get("page")); page > 0 && test.honor {
+				perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+				start, end := (page-1)*perPage, page*perPage
+				if start > len(res) {
+					start = len(res)
+				}
+				if end > len(res) {
+					end = len(res)
+				}
+				res = res[start:end]
+			}
+			json.NewEncoder(w).Encode(VmsResponse{DcResponse: DcResponse{Status: "SUCCESS"}, Result: res})
+		})
+
+		it := c.IterMachines(nil)
+		count := 0
+		for it.Next() && count <= test.machines {
+			if want := fmt.Sprintf("vm%03d", count); it.Value().Hostname != want {
+				t.Errorf("%s: machine %d is %s, want %s", test.name, count, it.Value().Hostname, want)
+			}
+			count++
+		}
+		if err := it.Err(); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+		if count != test.machines {
+			t.Errorf("%s: got %d machines, want %d", test.name, count, test.machines)
+		}
+		if requests != test.requests {
+			t.Errorf("%s: got %d requests, want %d", test.name, requests, test.requests)
+		}
+	}
+}
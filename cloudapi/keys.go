@@ -1,90 +1,247 @@
 package cloudapi
 
 import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/erigones/godanube/client"
 	"github.com/erigones/godanube/errors"
 )
 
+const (
+	// supported SSH public key types
+	KeyTypeRSA       = "ssh-rsa"
+	KeyTypeDSA       = "ssh-dss"
+	KeyTypeECDSA256  = "ecdsa-sha2-nistp256"
+	KeyTypeECDSA384  = "ecdsa-sha2-nistp384"
+	KeyTypeECDSA521  = "ecdsa-sha2-nistp521"
+	KeyTypeED25519   = "ssh-ed25519"
+	minRSAKeyBits    = 1024
+	ed25519KeyLength = 32
+)
+
 // Key represent a public key
 type Key struct {
-	Name        string // Name for the key
-	Fingerprint string // Key Fingerprint
-	Key         string // OpenSSH formatted public key
+	Name        string `json:"title"`                 // Name for the key
+	Fingerprint string `json:"fingerprint,omitempty"` // Key Fingerprint
+	Key         string `json:"key"`                   // OpenSSH formatted public key
 }
 
-/*func (k Key) Equals(other Key) bool {
-	if k.Name == other.Name && k.Fingerprint == other.Fingerprint && k.Key == other.Key {
-		return true
-	}
-	return false
-}*/
-
 // CreateKeyOpts represent the option that can be specified
 // when creating a new key.
 type CreateKeyOpts struct {
-	Name string `json:"name"` // Name for the key, optional
-	Key  string `json:"key"`  // OpenSSH formatted public key
+	ReqData
+	Name string `json:"-"`   // Name (title) for the key
+	Key  string `json:"key"` // OpenSSH formatted public key
+}
+
+// PublicKey represents a parsed OpenSSH public key
+type PublicKey struct {
+	Type    string // Key type, e.g. "ssh-rsa"
+	Comment string // Optional comment following the key
+	Bits    int    // Key size in bits (0 if not applicable)
+	blob    []byte // Key in SSH wire format
+}
+
+/*** STRUCTS FOR KEY-SPECIFIC DC RESPONSES ***/
+type KeyResponse struct {
+	DcResponse
+	Result Key `json:"result"`
+}
+
+type KeyResponseFull struct {
+	DcResponse
+	Result []Key `json:"result"`
 }
 
-// ListKeys returns a list of public keys registered with a specific account.
-// See API docs: http://apidocs.joyent.com/cloudapi/#ListKeys
-func (c *Client) ListKeys() ([]Key, error) {
-	var resp []Key
+// ListKeys returns the SSH public keys registered in the user profile.
+func (c *Client) ListKeys(username string) ([]Key, error) {
+	var resp KeyResponseFull
+	filter := NewFilter()
+	filter.Set("full", "true")
 	req := request{
 		method: client.GET,
-		url:    apiKeys,
+		url:    makeURL("accounts", "user", username, "sshkey"),
+		filter: filter,
 		resp:   &resp,
 	}
 	if _, err := c.sendRequest(req); err != nil {
-		return nil, errors.Newf(err, "failed to get list of keys")
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of keys of user \"%s\"", username)
 	}
-	return resp, nil
+	return resp.Result, nil
 }
 
-// GetKey returns the key identified by keyName.
-// See API docs: http://apidocs.joyent.com/cloudapi/#GetKey
-func (c *Client) GetKey(keyName string) (*Key, error) {
-	var resp Key
+// GetKey returns the key identified by keyName from the user profile.
+func (c *Client) GetKey(username, keyName string) (*Key, error) {
+	var resp KeyResponse
 	req := request{
 		method: client.GET,
-		url:    makeURL(apiKeys, keyName),
+		url:    makeURL("accounts", "user", username, "sshkey", keyName),
 		resp:   &resp,
 	}
 	if _, err := c.sendRequest(req); err != nil {
-		return nil, errors.Newf(err, "failed to get key with name: %s", keyName)
+		return nil, errors.Newf2(err, resp.Detail, "failed to get key \"%s\" of user \"%s\"", keyName, username)
 	}
-	return &resp, nil
+	return &resp.Result, nil
 }
 
-// CreateKey creates a new key with the specified options.
-// See API docs: http://apidocs.joyent.com/cloudapi/#CreateKey
-func (c *Client) CreateKey(opts CreateKeyOpts) (*Key, error) {
-	var resp Key
+// CreateKey adds a new key to the user profile.
+// The key is checked to be a supported public key before it is uploaded.
+func (c *Client) CreateKey(username string, opts CreateKeyOpts) (*Key, error) {
+	if _, err := ParsePublicKey(opts.Key); err != nil {
+		return nil, errors.NewInvalidArgumentf(err, "", "failed to create key \"%s\": invalid public key", opts.Name)
+	}
+	var resp KeyResponse
 	req := request{
-		method:         client.POST,
-		url:            apiKeys,
-		reqValue:       opts,
-		resp:           &resp,
-		expectedStatus: http.StatusCreated,
+		method:           client.POST,
+		url:              makeURL("accounts", "user", username, "sshkey", opts.Name),
+		reqValue:         &opts,
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
 	}
 	if _, err := c.sendRequest(req); err != nil {
-		return nil, errors.Newf(err, "failed to create key with name: %s", opts.Name)
+		return nil, errors.Newf2(err, resp.Detail, "failed to create key \"%s\" for user \"%s\"", opts.Name, username)
 	}
-	return &resp, nil
+	return &resp.Result, nil
 }
 
-// DeleteKey deletes the key identified by keyName.
-// See API docs: http://apidocs.joyent.com/cloudapi/#DeleteKey
-func (c *Client) DeleteKey(keyName string) error {
+// DeleteKey deletes the key identified by keyName from the user profile.
+func (c *Client) DeleteKey(username, keyName string) error {
+	var resp DcResponse
 	req := request{
-		method:         client.DELETE,
-		url:            makeURL(apiKeys, keyName),
-		expectedStatus: http.StatusNoContent,
+		method: client.DELETE,
+		url:    makeURL("accounts", "user", username, "sshkey", keyName),
+		resp:   &resp,
 	}
 	if _, err := c.sendRequest(req); err != nil {
-		return errors.Newf(err, "failed to delete key with name: %s", keyName)
+		return errors.Newf2(err, resp.Detail, "failed to delete key \"%s\" of user \"%s\"", keyName, username)
 	}
 	return nil
 }
+
+// ParsePublicKey parses a public key in OpenSSH authorized_keys format
+// ("<type> <base64 data> [comment]") and checks that it is of a supported type.
+func ParsePublicKey(key string) (*PublicKey, error) {
+	fields := strings.Fields(key)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("public key must be in format \"<type> <key> [comment]\"")
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("public key data is not valid base64: %v", err)
+	}
+
+	pk := &PublicKey{
+		Type:    fields[0],
+		Comment: strings.Join(fields[2:], " "),
+		blob:    blob,
+	}
+	keyType, rest, err := readSSHString(blob)
+	if err != nil {
+		return nil, err
+	}
+	if string(keyType) != pk.Type {
+		return nil, fmt.Errorf("public key type %q does not match key data type %q", pk.Type, keyType)
+	}
+
+	switch pk.Type {
+	case KeyTypeRSA:
+		var e, n []byte
+		if e, rest, err = readSSHString(rest); err == nil {
+			n, rest, err = readSSHString(rest)
+		}
+		if err != nil || len(e) == 0 {
+			return nil, fmt.Errorf("malformed %s key", pk.Type)
+		}
+		pk.Bits = len(bytes.TrimLeft(n, "\x00")) * 8
+		if pk.Bits < minRSAKeyBits {
+			return nil, fmt.Errorf("%s key is too short (%d bits)", pk.Type, pk.Bits)
+		}
+	case KeyTypeDSA:
+		for i := 0; i < 4 && err == nil; i++ { // p, q, g, y
+			var part []byte
+			if part, rest, err = readSSHString(rest); err == nil && i == 0 {
+				pk.Bits = len(bytes.TrimLeft(part, "\x00")) * 8
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("malformed %s key", pk.Type)
+		}
+	case KeyTypeECDSA256, KeyTypeECDSA384, KeyTypeECDSA521:
+		var curve, point []byte
+		if curve, rest, err = readSSHString(rest); err == nil {
+			point, rest, err = readSSHString(rest)
+		}
+		if err != nil || !strings.HasSuffix(pk.Type, string(curve)) || len(point) == 0 || point[0] != 4 {
+			return nil, fmt.Errorf("malformed %s key", pk.Type)
+		}
+		pk.Bits = map[string]int{KeyTypeECDSA256: 256, KeyTypeECDSA384: 384, KeyTypeECDSA521: 521}[pk.Type]
+	case KeyTypeED25519:
+		var point []byte
+		point, rest, err = readSSHString(rest)
+		if err != nil || len(point) != ed25519KeyLength {
+			return nil, fmt.Errorf("malformed %s key", pk.Type)
+		}
+		pk.Bits = 256
+	default:
+		return nil, fmt.Errorf("unsupported public key type %q", pk.Type)
+	}
+
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing data in %s key", pk.Type)
+	}
+	return pk, nil
+}
+
+// FingerprintMD5 returns the key fingerprint in the legacy OpenSSH format
+// (colon separated hex MD5 digest), as displayed by Danube Cloud.
+func (pk *PublicKey) FingerprintMD5() string {
+	sum := md5.Sum(pk.blob)
+	hexParts := make([]string, len(sum))
+	for i, b := range sum {
+		hexParts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(hexParts, ":")
+}
+
+// FingerprintSHA256 returns the key fingerprint in the current OpenSSH format ("SHA256:...").
+func (pk *PublicKey) FingerprintSHA256() string {
+	sum := sha256.Sum256(pk.blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// KeyFingerprintMD5 parses an OpenSSH public key and returns its MD5 fingerprint.
+func KeyFingerprintMD5(key string) (string, error) {
+	pk, err := ParsePublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	return pk.FingerprintMD5(), nil
+}
+
+// KeyFingerprintSHA256 parses an OpenSSH public key and returns its SHA256 fingerprint.
+func KeyFingerprintSHA256(key string) (string, error) {
+	pk, err := ParsePublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	return pk.FingerprintSHA256(), nil
+}
+
+// Helper to read a length-prefixed string from SSH wire format data.
+func readSSHString(data []byte) (value, rest []byte, err error) {
+	if len(data) < 4 {
+		return nil, nil, fmt.Errorf("malformed public key data")
+	}
+	length := binary.BigEndian.Uint32(data)
+	if uint64(len(data)-4) < uint64(length) {
+		return nil, nil, fmt.Errorf("malformed public key data")
+	}
+	return data[4 : 4+length], data[4+length:], nil
+}
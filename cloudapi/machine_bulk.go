@@ -0,0 +1,193 @@
+package cloudapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/erigones/godanube/errors"
+)
+
+// DefaultBulkConcurrency is the number of machines processed at once by bulk calls
+// when BulkOpts.Concurrency is not set
+const DefaultBulkConcurrency = 4
+
+// BulkOpts select the machines a bulk call runs on and how many of them are
+// processed at once. Machines listed in MachineIDs (hostnames or UUIDs) and machines
+// matching Filter (see ListMachinesFiltered()) are used together, each machine only once.
+// Destructive calls (stop, reboot, delete and BulkMachineAction()) reject an empty Filter
+// and match its Hostname, Alias and Node exactly instead of by substring.
+// All requests go through the client's rate limiter, so a higher concurrency
+// only helps with waiting for tasks, not with sending requests.
+type BulkOpts struct {
+	MachineIDs  []string
+	Filter      *VmDetails
+	Concurrency int
+}
+
+// BulkResult holds the outcome of a bulk call for every machine:
+// nil for success, or the error of the machine.
+type BulkResult map[string]error
+
+// Failed returns IDs of machines the call failed for, sorted.
+func (r BulkResult) Failed() []string {
+	var failed []string
+	for machineID, err := range r {
+		if err != nil {
+			failed = append(failed, machineID)
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
+
+// Err returns an error summarizing all failed machines, or nil if the call succeeded for all of them.
+func (r BulkResult) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(failed))
+	for i, machineID := range failed {
+		msgs[i] = fmt.Sprintf("%s: %v", machineID, r[machineID])
+	}
+	return errors.Newf(nil, "failed for %d of %d machines: %s", len(failed), len(r), strings.Join(msgs, "; "))
+}
+
+// StopMachines stops the selected machines (see StopMachine()).
+func (c *Client) StopMachines(opts BulkOpts, force bool) (BulkResult, error) {
+	return c.BulkMachineAction(opts, func(machineID string) error {
+		return c.StopMachine(machineID, force)
+	})
+}
+
+// StartMachines starts the selected machines (see StartMachine()).
+// An empty Filter selects all machines.
+func (c *Client) StartMachines(opts BulkOpts) (BulkResult, error) {
+	return c.bulkMachineAction(opts, false, c.StartMachine)
+}
+
+// RebootMachines reboots the selected machines (see RebootMachine()).
+func (c *Client) RebootMachines(opts BulkOpts) (BulkResult, error) {
+	return c.BulkMachineAction(opts, c.RebootMachine)
+}
+
+// DeleteMachines deletes the selected machines including their definitions (see DeleteMachine()).
+func (c *Client) DeleteMachines(opts BulkOpts, force bool) (BulkResult, error) {
+	return c.BulkMachineAction(opts, func(machineID string) error {
+		return c.DeleteMachine(machineID, force)
+	})
+}
+
+// BulkMachineAction runs action for every selected machine, at most opts.Concurrency
+// at once. A failure of one machine does not stop the others; the returned error
+// is set only if the machines could not be selected. The action is treated as
+// destructive (see BulkOpts).
+func (c *Client) BulkMachineAction(opts BulkOpts, action func(machineID string) error) (BulkResult, error) {
+	return c.bulkMachineAction(opts, true, action)
+}
+
+func (c *Client) bulkMachineAction(opts BulkOpts, destructive bool, action func(machineID string) error) (BulkResult, error) {
+	machineIDs, err := c.bulkMachineIDs(opts, destructive)
+	if err != nil {
+		return nil, err
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBulkConcurrency
+	}
+
+	result := make(BulkResult, len(machineIDs))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for _, machineID := range machineIDs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(machineID string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := action(machineID)
+			mu.Lock()
+			result[machineID] = err
+			mu.Unlock()
+		}(machineID)
+	}
+	wg.Wait()
+
+	return result, nil
+}
+
+// Helper that returns the machines selected by opts, each machine only once
+// (a machine given by its hostname and by its UUID is the same machine).
+func (c *Client) bulkMachineIDs(opts BulkOpts, destructive bool) ([]string, error) {
+	var q *MachineQuery
+	if opts.Filter != nil {
+		var err error
+		if q, err = bulkMachineQuery(*opts.Filter, destructive); err != nil {
+			return nil, err
+		}
+	}
+
+	// given machines need the list of all machines to get their UUIDs,
+	// the filter is then applied to it instead of asking the server again
+	machineIDs := opts.MachineIDs
+	uuids := make(map[string]string)
+	if len(opts.MachineIDs) > 0 {
+		vms, err := c.QueryMachines(nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, vm := range vms {
+			uuids[vm.Hostname] = vm.Uuid
+			uuids[vm.Uuid] = vm.Uuid
+		}
+		if q != nil {
+			machineIDs = append(append([]string{}, machineIDs...), GetVmUuids(q.apply(vms))...)
+		}
+	} else if q != nil {
+		filtered, err := c.QueryMachines(q)
+		if err != nil {
+			return nil, err
+		}
+		machineIDs = GetVmUuids(filtered)
+	}
+
+	seen := make(map[string]bool, len(machineIDs))
+	var unique []string
+	for _, machineID := range machineIDs {
+		uuid, ok := uuids[machineID]
+		if !ok {
+			// unknown machine (the action reports it) or a UUID from the filter
+			uuid = machineID
+		}
+		if !seen[uuid] {
+			seen[uuid] = true
+			unique = append(unique, machineID)
+		}
+	}
+	return unique, nil
+}
+
+// Helper that builds the query for BulkOpts.Filter.
+func bulkMachineQuery(vmfilter VmDetails, destructive bool) (*MachineQuery, error) {
+	if !destructive {
+		return MachineQueryFromDetails(vmfilter), nil
+	}
+	if vmfilter.Hostname == "" && vmfilter.Uuid == "" && vmfilter.Alias == "" && vmfilter.Node == "" &&
+		vmfilter.Owner == "" && vmfilter.Status == "" && len(vmfilter.Tags) == 0 {
+		return nil, errors.NewInvalidArgumentf(nil, "", "empty machine filter would select all machines")
+	}
+	q := MachineQueryFromDetails(vmfilter)
+	if vmfilter.Hostname != "" {
+		q.Where(func(vm *VmDetails) bool { return vm.Hostname == vmfilter.Hostname })
+	}
+	if vmfilter.Alias != "" {
+		q.Where(func(vm *VmDetails) bool { return vm.Alias == vmfilter.Alias })
+	}
+	if vmfilter.Node != "" {
+		q.Where(func(vm *VmDetails) bool { return vm.Node == vmfilter.Node })
+	}
+	return q, nil
+}
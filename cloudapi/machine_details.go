@@ -0,0 +1,189 @@
+package cloudapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/erigones/godanube/client"
+	"github.com/erigones/godanube/errors"
+)
+
+// MachineDetails is the complete view of a Danube machine: its status, definition,
+// disks and NICs. It takes the place of the Joyent Machine, which is kept only for
+// the firewall API until it is ported. Drift lists the definition changes that are not yet applied
+// to the deployed machine (what ApplyMachineChanges() would push).
+type MachineDetails struct {
+	VmDetails                    // status, node, IPs, usage counters, ...
+	Definition MachineDefinition // VM attributes incl. tags, mdata and routes
+	Disks      []VmDiskDefinition
+	Nics       []VmNicDefinition
+	Drift      []MachineChange // empty if the definition is applied
+}
+
+// MachineChange is a difference between the definition and the deployed machine
+type MachineChange struct {
+	Section   string      // "vm", "disk <id>" or "nic <id>"
+	Attribute string      // API attribute name (e.g. "ram"); empty if the whole disk or NIC is added or removed
+	Active    interface{} // value on the deployed machine (nil if not set)
+	Defined   interface{} // value in the definition (nil if not set)
+}
+
+func (ch MachineChange) String() string {
+	if ch.Attribute == "" {
+		switch {
+		case ch.Active == nil:
+			return fmt.Sprintf("%s: added", ch.Section)
+		case ch.Defined == nil:
+			return fmt.Sprintf("%s: removed", ch.Section)
+		}
+	}
+	return fmt.Sprintf("%s: %s: %v -> %v", ch.Section, ch.Attribute, ch.Active, ch.Defined)
+}
+
+// Changed tells whether the definition differs from the deployed machine.
+func (m *MachineDetails) Changed() bool {
+	return len(m.Drift) > 0
+}
+
+// GetMachineDetails returns the complete view of a machine. The deployed (active)
+// definition is fetched only if Danube reports pending changes.
+func (c *Client) GetMachineDetails(machineID string) (*MachineDetails, error) {
+	vm, err := c.GetMachine(machineID)
+	if err != nil {
+		return nil, err
+	}
+	m := &MachineDetails{VmDetails: *vm}
+	if err := c.getMachineDefinitions(machineID, false, &m.Definition, &m.Disks, &m.Nics); err != nil {
+		return nil, err
+	}
+	if !vm.Changed && !m.Definition.Changed {
+		return m, nil
+	}
+
+	var active MachineDefinition
+	var activeDisks []VmDiskDefinition
+	var activeNics []VmNicDefinition
+	if err := c.getMachineDefinitions(machineID, true, &active, &activeDisks, &activeNics); err != nil {
+		return nil, err
+	}
+	changes, err := compareDefinitions("vm", active, m.Definition)
+	if err != nil {
+		return nil, err
+	}
+	m.Drift = append(m.Drift, changes...)
+	for i := 0; i < len(activeDisks) || i < len(m.Disks); i++ {
+		changes, err := compareListItem(fmt.Sprintf("disk %d", i+1), i, activeDisks, m.Disks)
+		if err != nil {
+			return nil, err
+		}
+		m.Drift = append(m.Drift, changes...)
+	}
+	for i := 0; i < len(activeNics) || i < len(m.Nics); i++ {
+		changes, err := compareListItem(fmt.Sprintf("nic %d", i+1), i, activeNics, m.Nics)
+		if err != nil {
+			return nil, err
+		}
+		m.Drift = append(m.Drift, changes...)
+	}
+	return m, nil
+}
+
+// Helper that reads the current (or the deployed if active is true) definition of a machine.
+func (c *Client) getMachineDefinitions(machineID string, active bool, vm *MachineDefinition, disks *[]VmDiskDefinition, nics *[]VmNicDefinition) error {
+	filter := NewFilter()
+	if active {
+		filter.Set("active", "true")
+	}
+	var vmResp CreateMachineResponse
+	var diskResp VmDisksResponse
+	var nicResp VmNicsResponse
+	parts := []struct {
+		url  string
+		resp interface{}
+		dc   *DcResponse
+	}{
+		{makeURL("vm", machineID, "define"), &vmResp, &vmResp.DcResponse},
+		{makeURL("vm", machineID, "define", "disk"), &diskResp, &diskResp.DcResponse},
+		{makeURL("vm", machineID, "define", "nic"), &nicResp, &nicResp.DcResponse},
+	}
+	for _, part := range parts {
+		req := request{
+			method: client.GET,
+			url:    part.url,
+			filter: filter,
+			resp:   part.resp,
+		}
+		if _, err := c.sendRequest(req); err != nil {
+			return errors.Newf2(err, part.dc.Detail, "failed to get definition of machine \"%s\"", machineID)
+		}
+	}
+	*vm, *disks, *nics = vmResp.Result, diskResp.Result, nicResp.Result
+	return nil
+}
+
+// Helper that compares the i-th disk or NIC of the active and defined lists
+// (a slice of VmDiskDefinition or VmNicDefinition).
+func compareListItem(section string, i int, active, defined interface{}) ([]MachineChange, error) {
+	a, d := reflect.ValueOf(active), reflect.ValueOf(defined)
+	switch {
+	case i >= a.Len():
+		return []MachineChange{{Section: section, Defined: d.Index(i).Interface()}}, nil
+	case i >= d.Len():
+		return []MachineChange{{Section: section, Active: a.Index(i).Interface()}}, nil
+	}
+	return compareDefinitions(section, a.Index(i).Interface(), d.Index(i).Interface())
+}
+
+// Helper that compares two definitions attribute by attribute (by their JSON form,
+// so the attribute names are the API names).
+func compareDefinitions(section string, active, defined interface{}) ([]MachineChange, error) {
+	a, err := definitionAttributes(active)
+	if err != nil {
+		return nil, err
+	}
+	d, err := definitionAttributes(defined)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool)
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range d {
+		keys[k] = true
+	}
+	var names []string
+	for k := range keys {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var changes []MachineChange
+	for _, k := range names {
+		if !reflect.DeepEqual(a[k], d[k]) {
+			changes = append(changes, MachineChange{Section: section, Attribute: k, Active: a[k], Defined: d[k]})
+		}
+	}
+	return changes, nil
+}
+
+// API names of request and status attributes that are not part of a definition
+var definitionIgnoredAttributes = []string{"dc", "force", "uuid", "locked", "created", "changed"}
+
+// Helper that returns the attributes of a definition except request and status attributes.
+func definitionAttributes(definition interface{}) (map[string]interface{}, error) {
+	attrs := make(map[string]interface{})
+	data, err := json.Marshal(definition)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to compare definitions")
+	}
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		return nil, errors.Newf(err, "failed to compare definitions")
+	}
+	for _, k := range definitionIgnoredAttributes {
+		delete(attrs, k)
+	}
+	return attrs, nil
+}
@@ -1,70 +1,63 @@
 package cloudapi
 
-import (
-	"net/http"
-
-	"github.com/erigones/godanube/client"
-	"github.com/erigones/godanube/errors"
-)
+// machineMdataChange is the part of a machine definition changed by the mdata calls
+type machineMdataChange struct {
+	ReqData
+	Mdata map[string]string `json:"mdata"`
+}
 
-// UpdateMachineMetadata updates the metadata for a given machine.
-// Any metadata keys passed in here are created if they do not exist, and
-// overwritten if they do.
-// See API docs: http://apidocs.joyent.com/cloudapi/#UpdateMachineMetadata
-func (c *Client) UpdateMachineMetadata(machineID string, metadata map[string]string) (map[string]interface{}, error) {
-	var resp map[string]interface{}
-	req := request{
-		method:   client.POST,
-		url:      makeURL(apiMachines, machineID, apiMetadata),
-		reqValue: metadata,
-		resp:     &resp,
-	}
-	if _, err := c.sendRequest(req); err != nil {
-		return nil, errors.Newf(err, "failed to update metadata for machine with id %s", machineID)
+// GetMachineMdata returns the metadata (mdata) of a machine.
+func (c *Client) GetMachineMdata(machineID string) (map[string]string, error) {
+	definition, err := c.GetMachineDefinition(machineID)
+	if err != nil {
+		return nil, err
 	}
-	return resp, nil
+	return definition.Mdata, nil
 }
 
-// GetMachineMetadata returns the complete set of metadata associated with the
-// specified machine.
-// See API docs: http://apidocs.joyent.com/cloudapi/#GetMachineMetadata
-func (c *Client) GetMachineMetadata(machineID string) (map[string]interface{}, error) {
-	var resp map[string]interface{}
-	req := request{
-		method: client.GET,
-		url:    makeURL(apiMachines, machineID, apiMetadata),
-		resp:   &resp,
-	}
-	if _, err := c.sendRequest(req); err != nil {
-		return nil, errors.Newf(err, "failed to get list of metadata for machine with id %s", machineID)
-	}
-	return resp, nil
+// UpdateMachineMdata sets metadata keys of a machine; keys not in mdata are kept.
+// It returns the new metadata of the machine.
+func (c *Client) UpdateMachineMdata(machineID string, mdata map[string]string) (map[string]string, error) {
+	return c.modifyMachineMdata(machineID, func(current map[string]string) {
+		for key, value := range mdata {
+			current[key] = value
+		}
+	})
 }
 
-// DeleteMachineMetadata deletes a single metadata key from the specified machine.
-// See API docs: http://apidocs.joyent.com/cloudapi/#DeleteMachineMetadata
-func (c *Client) DeleteMachineMetadata(machineID, metadataKey string) error {
-	req := request{
-		method:         client.DELETE,
-		url:            makeURL(apiMachines, machineID, apiMetadata, metadataKey),
-		expectedStatus: http.StatusNoContent,
-	}
-	if _, err := c.sendRequest(req); err != nil {
-		return errors.Newf(err, "failed to delete metadata with key %s for machine with id %s", metadataKey, machineID)
-	}
-	return nil
+// DeleteMachineMdata removes metadata keys from a machine; missing keys are ignored.
+// It returns the new metadata of the machine.
+func (c *Client) DeleteMachineMdata(machineID string, keys ...string) (map[string]string, error) {
+	return c.modifyMachineMdata(machineID, func(current map[string]string) {
+		for _, key := range keys {
+			delete(current, key)
+		}
+	})
 }
 
-// DeleteAllMachineMetadata deletes all metadata keys from the specified machine.
-// See API docs: http://apidocs.joyent.com/cloudapi/#DeleteAllMachineMetadata
-func (c *Client) DeleteAllMachineMetadata(machineID string) error {
-	req := request{
-		method:         client.DELETE,
-		url:            makeURL(apiMachines, machineID, apiMetadata),
-		expectedStatus: http.StatusNoContent,
+// ReplaceMachineMdata replaces all metadata of a machine.
+func (c *Client) ReplaceMachineMdata(machineID string, mdata map[string]string) (map[string]string, error) {
+	if mdata == nil {
+		mdata = map[string]string{}
 	}
-	if _, err := c.sendRequest(req); err != nil {
-		return errors.Newf(err, "failed to delete metadata for machine with id %s", machineID)
+	definition, err := c.UpdateMachineDefinition(machineID, &machineMdataChange{Mdata: mdata})
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return definition.Mdata, nil
+}
+
+// Helper that changes the current metadata of a machine. The metadata are read and written
+// in two calls, so concurrent changes of the same machine may get lost.
+func (c *Client) modifyMachineMdata(machineID string, change func(current map[string]string)) (map[string]string, error) {
+	mdata, err := c.GetMachineMdata(machineID)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]string, len(mdata))
+	for key, value := range mdata {
+		current[key] = value
+	}
+	change(current)
+	return c.ReplaceMachineMdata(machineID, current)
 }
@@ -0,0 +1,413 @@
+package cloudapi
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/erigones/godanube/client"
+	"github.com/erigones/godanube/errors"
+)
+
+const (
+	// fields machines can be ordered by
+	VmOrderHostname = "hostname"
+	VmOrderUuid     = "uuid"
+	VmOrderAlias    = "alias"
+	VmOrderNode     = "node"
+	VmOrderOwner    = "owner"
+	VmOrderStatus   = "status"
+	VmOrderVcpus    = "vcpus"
+	VmOrderRam      = "ram"
+	VmOrderDisk     = "disk"
+)
+
+// fields machines can be ordered by; true if the Danube VM list supports
+// the field in its order_by parameter
+var vmOrderFields = map[string]bool{
+	VmOrderHostname: true,
+	VmOrderUuid:     true,
+	VmOrderAlias:    true,
+	VmOrderNode:     false,
+	VmOrderOwner:    false,
+	VmOrderStatus:   false,
+	VmOrderVcpus:    false,
+	VmOrderRam:      false,
+	VmOrderDisk:     false,
+}
+
+// MachineQuery selects, orders and pages machines for QueryMachines().
+// All conditions must match (AND). Conditions, orderings and paging the server
+// supports are sent to the server, the rest is applied by the client:
+//   - Uuid() reads the machine directly,
+//   - Owner(), State() with one state and Tags() filter on the server,
+//   - Hostname(), Alias() and Node() match substrings, which only the client can do,
+//   - Limit() is sent as per_page of the first page when all conditions and orderings
+//     are handled by the server and there is no Offset(); with an offset the client
+//     pages the whole list, because a later page cannot be told apart from the whole
+//     list returned by a server that ignores the paging parameters.
+//
+// Create it with NewMachineQuery() and chain the condition methods.
+type MachineQuery struct {
+	conds   []machineCond
+	uuid    string // machine read directly by Uuid()
+	orderBy []machineOrder
+	offset  int
+	limit   int
+	paged   bool  // offset and limit are sent as page and per_page also for a later page (see page())
+	err     error // invalid condition or ordering, returned by QueryMachines()
+}
+
+type machineCond struct {
+	param string // server-side filter parameter, empty if the server cannot filter by the field
+	value string
+	match func(vm *VmDetails) bool
+}
+
+type machineOrder struct {
+	field string
+	desc  bool
+}
+
+// NewMachineQuery returns a query matching all machines.
+func NewMachineQuery() *MachineQuery {
+	return &MachineQuery{}
+}
+
+// Hostname matches machines whose hostname contains substr.
+func (q *MachineQuery) Hostname(substr string) *MachineQuery {
+	return q.where(func(vm *VmDetails) bool { return strings.Contains(vm.Hostname, substr) })
+}
+
+// Uuid matches the machine with the given UUID (read directly by the server).
+func (q *MachineQuery) Uuid(uuid string) *MachineQuery {
+	if q.uuid == "" {
+		q.uuid = uuid
+	}
+	return q.where(func(vm *VmDetails) bool { return vm.Uuid == uuid })
+}
+
+// Alias matches machines whose alias contains substr.
+func (q *MachineQuery) Alias(substr string) *MachineQuery {
+	return q.where(func(vm *VmDetails) bool { return strings.Contains(vm.Alias, substr) })
+}
+
+// Node matches machines on compute nodes whose hostname contains substr.
+func (q *MachineQuery) Node(substr string) *MachineQuery {
+	return q.where(func(vm *VmDetails) bool { return strings.Contains(vm.Node, substr) })
+}
+
+// Owner matches machines owned by the user (filtered by the server).
+func (q *MachineQuery) Owner(owner string) *MachineQuery {
+	q.conds = append(q.conds, machineCond{
+		param: "owner",
+		value: owner,
+		match: func(vm *VmDetails) bool { return vm.Owner == owner },
+	})
+	return q
+}
+
+// State matches machines in any of the given states (filtered by the server if only one state is given).
+func (q *MachineQuery) State(states ...VmState) *MachineQuery {
+	cond := machineCond{
+		match: func(vm *VmDetails) bool {
+			for _, state := range states {
+				if vm.State() == state {
+					return true
+				}
+			}
+			return false
+		},
+	}
+	if len(states) == 1 {
+		cond.param, cond.value = "status", string(states[0])
+	}
+	q.conds = append(q.conds, cond)
+	return q
+}
+
+// Tags matches machines having all of the given tags (filtered by the server).
+func (q *MachineQuery) Tags(tags ...string) *MachineQuery {
+	for _, tag := range tags {
+		tag := tag
+		q.conds = append(q.conds, machineCond{
+			param: "tag",
+			value: tag,
+			match: func(vm *VmDetails) bool { return hasAllTags(vm.Tags, []string{tag}) },
+		})
+	}
+	return q
+}
+
+// Where matches machines for which match returns true (always applied by the client).
+func (q *MachineQuery) Where(match func(vm *VmDetails) bool) *MachineQuery {
+	return q.where(match)
+}
+
+// OrderBy sorts the machines by a field (VmOrder*); more calls add secondary orderings.
+// QueryMachines() fails for an unknown field.
+func (q *MachineQuery) OrderBy(field string, desc bool) *MachineQuery {
+	if _, ok := vmOrderFields[field]; !ok && q.err == nil {
+		q.err = errors.NewInvalidArgumentf(nil, "", "machines cannot be ordered by \"%s\"", field)
+	}
+	q.orderBy = append(q.orderBy, machineOrder{field, desc})
+	return q
+}
+
+// Offset skips the first n matching machines.
+func (q *MachineQuery) Offset(n int) *MachineQuery {
+	q.offset = n
+	return q
+}
+
+// Limit returns at most n matching machines (0 means no limit).
+func (q *MachineQuery) Limit(n int) *MachineQuery {
+	q.limit = n
+	return q
+}
+
+// QueryMachines returns details of machines matching the query.
+func (c *Client) QueryMachines(q *MachineQuery) ([]VmDetails, error) {
+	if q == nil {
+		q = NewMachineQuery()
+	}
+	vms, err := c.fetchMachines(q)
+	if err != nil {
+		return nil, err
+	}
+	// the first page from the server is the same as the first page taken by the client
+	return q.apply(vms), nil
+}
+
+// Helper that returns machines from the server for the query (filtered only by the
+// server-side conditions).
+func (c *Client) fetchMachines(q *MachineQuery) ([]VmDetails, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	if q.uuid != "" {
+		vm, err := c.GetMachine(q.uuid)
+		if err != nil {
+			if errors.IsResourceNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return []VmDetails{*vm}, nil
+	}
+
+	var resp VmsResponse
+	req := request{
+		method: client.GET,
+		url:    "vm",
+		filter: q.serverFilter(),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of machines")
+	}
+	return resp.Result, nil
+}
+
+// Helper that returns a copy of the query requesting one page of size machines (counted
+// from 1) from the server. The caller must check that the server did not ignore paging.
+func (q *MachineQuery) page(page, size int) *MachineQuery {
+	p := *q
+	p.offset = (page - 1) * size
+	p.limit = size
+	p.paged = true
+	return &p
+}
+
+// MachineQueryFromDetails returns a query matching machines by the set fields of vmfilter
+// (see ListMachinesFilteredFull() for how the fields are compared).
+func MachineQueryFromDetails(vmfilter VmDetails) *MachineQuery {
+	q := NewMachineQuery()
+	if vmfilter.Hostname != "" {
+		q.Hostname(vmfilter.Hostname)
+	}
+	if vmfilter.Uuid != "" {
+		q.Uuid(vmfilter.Uuid)
+	}
+	if vmfilter.Alias != "" {
+		q.Alias(vmfilter.Alias)
+	}
+	if vmfilter.Node != "" {
+		q.Node(vmfilter.Node)
+	}
+	if vmfilter.Owner != "" {
+		q.Owner(vmfilter.Owner)
+	}
+	if vmfilter.Status != "" {
+		q.State(vmfilter.State())
+	}
+	if len(vmfilter.Tags) > 0 {
+		q.Tags(vmfilter.Tags...)
+	}
+	return q
+}
+
+func (q *MachineQuery) where(match func(vm *VmDetails) bool) *MachineQuery {
+	q.conds = append(q.conds, machineCond{match: match})
+	return q
+}
+
+// Helper that builds the request parameters for the conditions and orderings
+// the server can handle.
+func (q *MachineQuery) serverFilter() *Filter {
+	filter := NewFilter()
+	filter.Set("extended", "true")
+	for _, cond := range q.conds {
+		if cond.param != "" {
+			filter.Add(cond.param, cond.value)
+		}
+	}
+	if q.serverOrdered() {
+		var fields []string
+		for _, o := range q.orderBy {
+			if o.desc {
+				fields = append(fields, "-"+o.field)
+			} else {
+				fields = append(fields, o.field)
+			}
+		}
+		filter.Set("order_by", strings.Join(fields, ","))
+	}
+	if q.serverPaged() {
+		filter.Set("page", strconv.Itoa(q.offset/q.limit+1))
+		filter.Set("per_page", strconv.Itoa(q.limit))
+	}
+	return filter
+}
+
+// Helper that tells whether the whole ordering can be done by the server.
+func (q *MachineQuery) serverOrdered() bool {
+	if len(q.orderBy) == 0 {
+		return false
+	}
+	for _, o := range q.orderBy {
+		if !vmOrderFields[o.field] {
+			return false
+		}
+	}
+	return true
+}
+
+// Helper that tells whether the paging is sent to the server: all conditions and
+// orderings are handled by the server and the query selects the first page (or
+// a page requested by page()).
+func (q *MachineQuery) serverPaged() bool {
+	if q.limit <= 0 || q.offset%q.limit != 0 || q.uuid != "" {
+		return false
+	}
+	if q.offset > 0 && !q.paged {
+		return false
+	}
+	if len(q.orderBy) > 0 && !q.serverOrdered() {
+		return false
+	}
+	for _, cond := range q.conds {
+		if cond.param == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Helper that applies the conditions, ordering and paging to machines returned by
+// the server. Server-side conditions are checked again, which is cheap and keeps
+// the result correct even if the server ignored a parameter.
+func (q *MachineQuery) apply(vms []VmDetails) []VmDetails {
+	var found []VmDetails
+	for i := range vms {
+		if q.matches(&vms[i]) {
+			found = append(found, vms[i])
+		}
+	}
+	if len(q.orderBy) > 0 && !q.serverOrdered() {
+		sort.SliceStable(found, func(i, j int) bool { return q.less(&found[i], &found[j]) })
+	}
+	if q.offset > 0 {
+		if q.offset >= len(found) {
+			return nil
+		}
+		found = found[q.offset:]
+	}
+	if q.limit > 0 && q.limit < len(found) {
+		found = found[:q.limit]
+	}
+	return found
+}
+
+func (q *MachineQuery) matches(vm *VmDetails) bool {
+	for _, cond := range q.conds {
+		if !cond.match(vm) {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *MachineQuery) less(a, b *VmDetails) bool {
+	for _, o := range q.orderBy {
+		cmp := compareVmField(a, b, o.field)
+		if cmp == 0 {
+			continue
+		}
+		if o.desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	}
+	return false
+}
+
+// Helper that compares a field of two machines (-1, 0, +1).
+func compareVmField(a, b *VmDetails, field string) int {
+	var x, y int
+	var s, t string
+	switch field {
+	case VmOrderHostname:
+		s, t = a.Hostname, b.Hostname
+	case VmOrderUuid:
+		s, t = a.Uuid, b.Uuid
+	case VmOrderAlias:
+		s, t = a.Alias, b.Alias
+	case VmOrderNode:
+		s, t = a.Node, b.Node
+	case VmOrderOwner:
+		s, t = a.Owner, b.Owner
+	case VmOrderStatus:
+		s, t = a.Status, b.Status
+	case VmOrderVcpus:
+		x, y = a.Vcpus, b.Vcpus
+	case VmOrderRam:
+		x, y = a.Ram, b.Ram
+	case VmOrderDisk:
+		x, y = a.Disk, b.Disk
+	}
+	switch {
+	case s < t || x < y:
+		return -1
+	case s > t || x > y:
+		return 1
+	}
+	return 0
+}
+
+// Helper that tells whether all wanted tags are among tags.
+func hasAllTags(tags, wanted []string) bool {
+	for _, w := range wanted {
+		found := false
+		for _, tag := range tags {
+			if tag == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
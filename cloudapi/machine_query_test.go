@@ -0,0 +1,152 @@
+package cloudapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func testVms() []VmDetails {
+	return []VmDetails{
+		{Hostname: "web10", Uuid: "u3", Node: "node2", Status: "running", Ram: 2048, Tags: []string{"web"}},
+		{Hostname: "web1", Uuid: "u1", Node: "node1", Status: "stopped", Ram: 1024, Tags: []string{"web", "prod"}},
+		{Hostname: "db1", Uuid: "u2", Node: "node1", Status: "running", Ram: 4096, Tags: []string{"db", "prod"}},
+		{Hostname: "web2", Uuid: "u4", Node: "node2", Status: "running", Ram: 1024},
+	}
+}
+
+func hostnames(vms []VmDetails) []string {
+	names := []string{}
+	for _, vm := range vms {
+		names = append(names, vm.Hostname)
+	}
+	return names
+}
+
+func TestMachineQueryApply(t *testing.T) {
+	tests := []struct {
+		name string
+		q    *MachineQuery
+		want []string
+	}{
+		{"all", NewMachineQuery(), []string{"web10", "web1", "db1", "web2"}},
+		{"and", NewMachineQuery().Hostname("web").State(VmStateRunning), []string{"web10", "web2"}},
+		{"tags", NewMachineQuery().Tags("web", "prod"), []string{"web1"}},
+		{"uuid", NewMachineQuery().Uuid("u2"), []string{"db1"}},
+		{"where", NewMachineQuery().Where(func(vm *VmDetails) bool { return vm.Ram > 1024 }), []string{"web10", "db1"}},
+		{"order", NewMachineQuery().OrderBy(VmOrderRam, false), []string{"web1", "web2", "web10", "db1"}},
+		{"server order kept", NewMachineQuery().OrderBy(VmOrderHostname, false), []string{"web10", "web1", "db1", "web2"}},
+		{"order desc", NewMachineQuery().OrderBy(VmOrderRam, true), []string{"db1", "web10", "web1", "web2"}},
+		{"secondary order", NewMachineQuery().OrderBy(VmOrderNode, false).OrderBy(VmOrderRam, true), []string{"db1", "web1", "web10", "web2"}},
+		{"offset limit", NewMachineQuery().OrderBy(VmOrderRam, false).Offset(1).Limit(2), []string{"web2", "web10"}},
+		{"offset past end", NewMachineQuery().Offset(10), []string{}},
+	}
+	for _, test := range tests {
+		got := hostnames(test.q.apply(testVms()))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMachineQueryServerPaged(t *testing.T) {
+	tests := []struct {
+		name string
+		q    *MachineQuery
+		want bool
+	}{
+		{"no limit", NewMachineQuery(), false},
+		{"first page", NewMachineQuery().Limit(10), true},
+		{"offset", NewMachineQuery().Offset(10).Limit(10), false},
+		{"requested page", NewMachineQuery().page(2, 10), true},
+		{"odd offset of requested page", NewMachineQuery().page(2, 10).Offset(5), false},
+		{"client-side condition", NewMachineQuery().Hostname("web").Limit(10), false},
+		{"client-side order", NewMachineQuery().OrderBy(VmOrderRam, false).Limit(10), false},
+		{"server order", NewMachineQuery().OrderBy(VmOrderHostname, false).Limit(10), true},
+		{"uuid", NewMachineQuery().Uuid("u1").Limit(10), false},
+	}
+	for _, test := range tests {
+		if got := test.q.serverPaged(); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+		if sent := test.q.serverFilter().v.Get("page") != ""; sent != test.want {
+			t.Errorf("%s: page sent %v, want %v", test.name, sent, test.want)
+		}
+	}
+}
+
+func TestQueryMachinesServerIgnoresPaging(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(VmsResponse{DcResponse: DcResponse{Status: "SUCCESS"}, Result: testVms()})
+	})
+	tests := []struct {
+		name string
+		q    *MachineQuery
+		want []string
+	}{
+		{"first page", NewMachineQuery().Limit(2), []string{"web10", "web1"}},
+		{"second page", NewMachineQuery().Offset(2).Limit(2), []string{"db1", "web2"}},
+		{"offset past end", NewMachineQuery().Offset(10).Limit(10), []string{}},
+	}
+	for _, test := range tests {
+		vms, err := c.QueryMachines(test.q)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got := hostnames(vms); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMachineQueryServerFilter(t *testing.T) {
+	q := NewMachineQuery().Owner("admin").State(VmStateRunning).Tags("web").OrderBy(VmOrderHostname, true).page(3, 10)
+	params := q.serverFilter().v
+	want := map[string][]string{
+		"extended": {"true"},
+		"owner":    {"admin"},
+		"status":   {"running"},
+		"tag":      {"web"},
+		"order_by": {"-hostname"},
+		"page":     {"3"},
+		"per_page": {"10"},
+	}
+	if !reflect.DeepEqual(map[string][]string(params), want) {
+		t.Errorf("got %v, want %v", params, want)
+	}
+
+	// substring match is done by the client, so the server cannot page
+	q = NewMachineQuery().Hostname("web").Limit(10)
+	if q.serverPaged() {
+		t.Errorf("query with a client-side condition is paged by the server")
+	}
+	if q.serverFilter().v.Get("page") != "" {
+		t.Errorf("page sent for a query with a client-side condition")
+	}
+}
+
+func TestMachineQueryOrderByUnknownField(t *testing.T) {
+	q := NewMachineQuery().OrderBy("color", false)
+	if q.err == nil {
+		t.Errorf("no error for unknown order field")
+	}
+}
+
+func TestCompareVmField(t *testing.T) {
+	a := &VmDetails{Hostname: "a", Vcpus: 2}
+	b := &VmDetails{Hostname: "b", Vcpus: 1}
+	tests := []struct {
+		field string
+		want  int
+	}{
+		{VmOrderHostname, -1},
+		{VmOrderVcpus, 1},
+		{VmOrderRam, 0},
+	}
+	for _, test := range tests {
+		if got := compareVmField(a, b, test.field); got != test.want {
+			t.Errorf("%s: got %d, want %d", test.field, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package cloudapi
+
+import (
+	"strconv"
+
+	"github.com/erigones/godanube/client"
+	"github.com/erigones/godanube/errors"
+)
+
+// ResizeMachineOpts represents the new size of a machine for ResizeMachine().
+// Zero values keep the current size.
+type ResizeMachineOpts struct {
+	Vcpus     int         // number of vCPUs
+	Ram       int         // RAM in MB
+	DiskSizes map[int]int // new disk sizes in MB by disk ID (VmDiskDefinition.DiskId); disks can only grow
+
+	// Template takes the sizes not set above from a template (vm_define and vm_define_disk),
+	// so that it can be used as a size profile (like a Joyent package).
+	Template string
+
+	// AllowRestart stops the machine if the change cannot be applied while it is running
+	// (vCPUs, RAM or disks of a KVM machine) and starts it again afterwards. Without it
+	// such change is only saved into the definition and applied on the next start.
+	AllowRestart bool
+}
+
+// resizeVmDefine holds the VM attributes changed by ResizeMachine()
+type resizeVmDefine struct {
+	ReqData
+	Vcpus int `json:"vcpus,omitempty"`
+	Ram   int `json:"ram,omitempty"`
+}
+
+// resizeDiskDefine holds the disk attributes changed by ResizeMachine()
+type resizeDiskDefine struct {
+	ReqData
+	Size int `json:"size"`
+}
+
+// ResizeMachine changes vCPUs, RAM and disk sizes of a machine. The new size is checked
+// against free resources of the machine's compute node in the current vDC, saved into
+// the machine definition and applied to a deployed machine (see ResizeMachineOpts.AllowRestart).
+func (c *Client) ResizeMachine(machineID string, opts ResizeMachineOpts) error {
+	vm, err := c.GetMachine(machineID)
+	if err != nil {
+		return err
+	}
+	definition, err := c.GetMachineDefinition(machineID)
+	if err != nil {
+		return err
+	}
+	disks, err := c.GetMachineDisks(machineID)
+	if err != nil {
+		return err
+	}
+	if opts.Template != "" {
+		if err := c.fillResizeFromTemplate(&opts); err != nil {
+			return err
+		}
+	}
+
+	// changes to apply
+	var vmChange resizeVmDefine
+	vcpuGrowth, ramGrowth := 0, 0
+	if opts.Vcpus > 0 && opts.Vcpus != definition.Vcpus {
+		vmChange.Vcpus = opts.Vcpus
+		vcpuGrowth = opts.Vcpus - definition.Vcpus
+	}
+	if opts.Ram > 0 && opts.Ram != definition.Ram {
+		vmChange.Ram = opts.Ram
+		ramGrowth = opts.Ram - definition.Ram
+	}
+	diskSizes := make(map[int]int)
+	for _, disk := range disks {
+		diskSizes[disk.DiskId] = disk.Size
+	}
+	diskChanges := make(map[int]int)
+	diskGrowth := 0
+	for diskID, size := range opts.DiskSizes {
+		current, ok := diskSizes[diskID]
+		if !ok {
+			return errors.NewInvalidArgumentf(nil, "", "machine \"%s\" has no disk %d", machineID, diskID)
+		}
+		if size < current {
+			return errors.NewInvalidArgumentf(nil, "", "disk %d of machine \"%s\" cannot be shrunk (%d MB -> %d MB)", diskID, machineID, current, size)
+		}
+		if size > current {
+			diskChanges[diskID] = size
+			diskGrowth += size - current
+		}
+	}
+	if vmChange.Vcpus == 0 && vmChange.Ram == 0 && len(diskChanges) == 0 {
+		return nil
+	}
+
+	if err := c.checkNodeResources(vm.Node, vcpuGrowth, ramGrowth, diskGrowth); err != nil {
+		return err
+	}
+
+	state, err := c.waitForStableState(machineID, VmStatusTimeout)
+	if err != nil {
+		return err
+	}
+	// zones can be resized while running, KVM machines must be stopped
+	offline := definition.OsType != OsTypeSunosZone && definition.OsType != OsTypeLinuxZone
+	restart := offline && state.IsRunning() && opts.AllowRestart
+	if restart {
+		if err := c.StopMachine(machineID, false); err != nil {
+			return errors.Newf(err, "failed to stop machine \"%s\" before resize", machineID)
+		}
+	}
+
+	err = c.resizeMachineDefinition(machineID, &vmChange, diskChanges)
+	switch {
+	case restart:
+		// pending changes are applied when the machine starts; it is started
+		// also if the resize failed, so that it is not left stopped
+		if startErr := c.StartMachine(machineID); err == nil {
+			err = startErr
+		}
+		return err
+	case err != nil:
+		return err
+	case state.CanDeploy():
+		return nil
+	case offline && state.IsRunning():
+		// applied on the next start
+		return nil
+	}
+	return c.ApplyMachineChanges(machineID)
+}
+
+// Helper that saves the new vCPUs, RAM and disk sizes into the machine definition.
+func (c *Client) resizeMachineDefinition(machineID string, vmChange *resizeVmDefine, diskChanges map[int]int) error {
+	if vmChange.Vcpus != 0 || vmChange.Ram != 0 {
+		if _, err := c.updateMachineDefinition(machineID, vmChange); err != nil {
+			return err
+		}
+	}
+	for diskID, size := range diskChanges {
+		if err := c.resizeMachineDisk(machineID, diskID, size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Helper that sets the sizes not given in opts from a template.
+func (c *Client) fillResizeFromTemplate(opts *ResizeMachineOpts) error {
+	t, err := c.GetTemplate(opts.Template)
+	if err != nil {
+		return err
+	}
+	if opts.Vcpus == 0 {
+		opts.Vcpus = t.VmDefine.Vcpus
+	}
+	if opts.Ram == 0 {
+		opts.Ram = t.VmDefine.Ram
+	}
+	sizes := make(map[int]int)
+	for i, disk := range t.VmDefineDisk {
+		diskID := disk.DiskId
+		if diskID == 0 {
+			// template disks without an ID are in the order of machine disks
+			diskID = i + 1
+		}
+		if disk.Size > 0 {
+			sizes[diskID] = disk.Size
+		}
+	}
+	for diskID, size := range opts.DiskSizes {
+		sizes[diskID] = size
+	}
+	opts.DiskSizes = sizes
+	return nil
+}
+
+// Helper that checks whether the compute node has enough free resources in the current vDC
+// for the growth of the machine. Nothing is checked for a machine without a node.
+func (c *Client) checkNodeResources(nodeName string, vcpus, ram, disk int) error {
+	if nodeName == "" || (vcpus <= 0 && ram <= 0 && disk <= 0) {
+		return nil
+	}
+	nodes, err := c.GetDatacenterNodes(c.GetVirtDC())
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if node.Hostname != nodeName {
+			continue
+		}
+		switch {
+		case vcpus > node.CpuFree:
+			return errors.NewInvalidArgumentf(nil, "", "not enough free vCPUs on node \"%s\" (%d needed, %d free)", nodeName, vcpus, node.CpuFree)
+		case ram > node.RamFree:
+			return errors.NewInvalidArgumentf(nil, "", "not enough free RAM on node \"%s\" (%d MB needed, %d MB free)", nodeName, ram, node.RamFree)
+		case disk > node.DiskFree:
+			return errors.NewInvalidArgumentf(nil, "", "not enough free disk space on node \"%s\" (%d MB needed, %d MB free)", nodeName, disk, node.DiskFree)
+		}
+		return nil
+	}
+	return errors.NewInvalidArgumentf(nil, "", "node \"%s\" is not attached to virtual datacenter \"%s\"", nodeName, c.GetVirtDC())
+}
+
+// Helper that changes the size of a disk in the machine definition.
+func (c *Client) resizeMachineDisk(machineID string, diskID, size int) error {
+	var resp DcResponse
+	req := request{
+		method:   client.PUT,
+		url:      makeURL("vm", machineID, "define", "disk", strconv.Itoa(diskID)),
+		reqValue: &resizeDiskDefine{Size: size},
+		resp:     &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return errors.Newf2(err, resp.Detail, "failed to resize disk %d of machine \"%s\"", diskID, machineID)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package cloudapi
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/erigones/godanube/errors"
+)
+
+// VmState represents the status of a machine as reported by Danube Cloud
+type VmState string
+
+const (
+	VmStateNotCreated VmState = "notcreated" // only the definition exists
+	VmStateCreating   VmState = "creating"
+	VmStateDeploying  VmState = "deploying"
+	VmStateNotReady   VmState = "notready" // created, but not available (e.g. after a failed destroy or a node problem)
+	VmStateRunning    VmState = "running"
+	VmStateStarting   VmState = "starting"
+	VmStateStopping   VmState = "stopping"
+	VmStateStopped    VmState = "stopped"
+	VmStateFrozen     VmState = "frozen" // stopped and cannot be started until unfrozen
+	VmStatePending    VmState = "pending"
+	VmStateError      VmState = "error"
+	VmStateUnknown    VmState = "unknown"
+
+	// suffix of a state that is being changed by a running task (e.g. "running-")
+	vmStateChangingSuffix = "-"
+)
+
+// vmStateTransitions lists states a machine can get to from a stable state
+// by a lifecycle call (deploy, start, stop, freeze, destroy, ...).
+// All lifecycle calls check it before they start (see waitForTransition()).
+var vmStateTransitions = map[VmState][]VmState{
+	VmStateNotCreated: {VmStateDeploying, VmStateCreating},
+	VmStateRunning:    {VmStateStopping, VmStateStopped, VmStateFrozen, VmStateRunning}, // reboot keeps it running
+	VmStateStopped:    {VmStateStarting, VmStateRunning, VmStateFrozen, VmStateNotReady, VmStateNotCreated},
+	VmStateFrozen:     {VmStateStopped, VmStateStarting, VmStateRunning, VmStateNotCreated},
+	VmStateError:      {VmStateNotReady, VmStateNotCreated, VmStateStopped, VmStateRunning},
+	VmStateNotReady:   {VmStateStopped, VmStateRunning, VmStateNotCreated, VmStateError},
+}
+
+// Base returns the state without the suffix marking a pending change ("running-" -> "running").
+func (s VmState) Base() VmState {
+	return VmState(strings.TrimSuffix(string(s), vmStateChangingSuffix))
+}
+
+// IsTransient tells whether the machine is in the middle of a state change and
+// no lifecycle action should be started until it finishes. A machine in the notready
+// state may stay there until it is destroyed, so the state is not transient.
+func (s VmState) IsTransient() bool {
+	if strings.HasSuffix(string(s), vmStateChangingSuffix) {
+		return true
+	}
+	switch s {
+	case VmStateCreating, VmStateDeploying, VmStateStarting, VmStateStopping, VmStatePending:
+		return true
+	}
+	return false
+}
+
+// IsRunning tells whether the machine is running (and no change of its state is pending).
+func (s VmState) IsRunning() bool {
+	return s == VmStateRunning
+}
+
+// IsStopped tells whether the machine is stopped or frozen (and no change of its state is pending).
+func (s VmState) IsStopped() bool {
+	return s == VmStateStopped || s == VmStateFrozen
+}
+
+// CanDeploy tells whether the machine can be deployed (it exists only as a definition).
+func (s VmState) CanDeploy() bool {
+	return !s.IsTransient() && s.CanTransition(VmStateDeploying)
+}
+
+// CanDestroy tells whether the machine data can be destroyed (DestroyMachine())
+// without stopping it first.
+func (s VmState) CanDestroy() bool {
+	return !s.IsTransient() && s != VmStateNotCreated && s.CanTransition(VmStateNotCreated)
+}
+
+// CanTransition tells whether the machine can get from state s to state to
+// by a lifecycle call. Transient states can only finish to their target state,
+// so they are checked by their stable base state.
+func (s VmState) CanTransition(to VmState) bool {
+	from := s.Base()
+	if from == to.Base() {
+		return true
+	}
+	for _, state := range vmStateTransitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// State returns the typed state of the machine.
+func (vm *VmDetails) State() VmState {
+	return VmState(vm.Status)
+}
+
+// GetMachineVmState returns the typed state of a machine.
+func (c *Client) GetMachineVmState(machineID string) (VmState, error) {
+	state, err := c.GetMachineState(machineID)
+	if err != nil {
+		return "", err
+	}
+	return VmState(*state), nil
+}
+
+// WaitForState polls the state of the machine until predicate is true for it
+// and returns the state. An error is returned if ctx expires first
+// (together with the last seen state).
+func (c *Client) WaitForState(ctx context.Context, machineID string, predicate func(VmState) bool) (VmState, error) {
+	for {
+		state, err := c.GetMachineVmState(machineID)
+		if err != nil {
+			return state, err
+		}
+		if predicate(state) {
+			return state, nil
+		}
+		select {
+		case <-ctx.Done():
+			return state, errors.Newf(ctx.Err(), "timed out waiting for machine \"%s\" (state \"%s\")", machineID, state)
+		case <-time.After(TaskQuerySleepTime * time.Second):
+		}
+	}
+}
+
+// Helper that waits (at most timeoutSec task query cycles) until the machine finishes
+// a running state change and checks that a lifecycle call can get it to the state to.
+func (c *Client) waitForTransition(machineID string, to VmState, timeoutSec uint) (VmState, error) {
+	state, err := c.waitForStableState(machineID, timeoutSec)
+	if err != nil {
+		return state, err
+	}
+	if !state.CanTransition(to) {
+		return state, errors.NewInvalidArgumentf(nil, "", "machine \"%s\" cannot get from state \"%s\" to \"%s\"", machineID, state, to)
+	}
+	return state, nil
+}
+
+// Helper that waits (at most timeoutSec task query cycles) until the machine
+// finishes a running state change, so that a lifecycle action can be started.
+func (c *Client) waitForStableState(machineID string, timeoutSec uint) (VmState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec*TaskQuerySleepTime)*time.Second)
+	defer cancel()
+	return c.WaitForState(ctx, machineID, func(s VmState) bool { return !s.IsTransient() })
+}
@@ -1,103 +1,77 @@
 package cloudapi
 
-import (
-	"net/http"
+import "sort"
 
-	"github.com/erigones/godanube/client"
-	"github.com/erigones/godanube/errors"
-)
-
-// AddMachineTags adds additional tags to the specified machine.
-// This API lets you append new tags, not overwrite existing tags.
-// See API docs: http://apidocs.joyent.com/cloudapi/#AddMachineTags
-func (c *Client) AddMachineTags(machineID string, tags map[string]string) (map[string]string, error) {
-	var resp map[string]string
-	req := request{
-		method:   client.POST,
-		url:      makeURL(apiMachines, machineID, apiTags),
-		reqValue: tags,
-		resp:     &resp,
-	}
-	if _, err := c.sendRequest(req); err != nil {
-		return nil, errors.Newf(err, "failed to add tags for machine with id %s", machineID)
-	}
-	return resp, nil
+// machineTagsChange is the part of a machine definition changed by the tag calls
+type machineTagsChange struct {
+	ReqData
+	Tags []string `json:"tags"`
 }
 
-// ReplaceMachineTags replaces existing tags for the specified machine.
-// This API lets you overwrite existing tags, not append to existing tags.
-// See API docs: http://apidocs.joyent.com/cloudapi/#ReplaceMachineTags
-func (c *Client) ReplaceMachineTags(machineID string, tags map[string]string) (map[string]string, error) {
-	var resp map[string]string
-	req := request{
-		method:   client.PUT,
-		url:      makeURL(apiMachines, machineID, apiTags),
-		reqValue: tags,
-		resp:     &resp,
+// ListMachineTags returns the tags of a machine.
+func (c *Client) ListMachineTags(machineID string) ([]string, error) {
+	definition, err := c.GetMachineDefinition(machineID)
+	if err != nil {
+		return nil, err
 	}
-	if _, err := c.sendRequest(req); err != nil {
-		return nil, errors.Newf(err, "failed to replace tags for machine with id %s", machineID)
-	}
-	return resp, nil
+	return definition.Tags, nil
 }
 
-// ListMachineTags returns the complete set of tags associated with the specified machine.
-// See API docs: http://apidocs.joyent.com/cloudapi/#ListMachineTags
-func (c *Client) ListMachineTags(machineID string) (map[string]string, error) {
-	var resp map[string]string
-	req := request{
-		method: client.GET,
-		url:    makeURL(apiMachines, machineID, apiTags),
-		resp:   &resp,
-	}
-	if _, err := c.sendRequest(req); err != nil {
-		return nil, errors.Newf(err, "failed to get list of tags for machine with id %s", machineID)
-	}
-	return resp, nil
+// AddMachineTags adds tags to a machine; tags the machine already has are kept.
+// It returns the new tags of the machine.
+func (c *Client) AddMachineTags(machineID string, tags ...string) ([]string, error) {
+	return c.modifyMachineTags(machineID, func(current map[string]bool) {
+		for _, tag := range tags {
+			current[tag] = true
+		}
+	})
 }
 
-// GetMachineTag returns the value for a single tag on the specified machine.
-// See API docs: http://apidocs.joyent.com/cloudapi/#GetMachineTag
-func (c *Client) GetMachineTag(machineID, tagKey string) (string, error) {
-	var resp []byte
-	requestHeaders := make(http.Header)
-	requestHeaders.Set("Accept", "text/plain")
-	req := request{
-		method:    client.GET,
-		url:       makeURL(apiMachines, machineID, apiTags, tagKey),
-		resp:      &resp,
-		reqHeader: requestHeaders,
-	}
-	if _, err := c.sendRequest(req); err != nil {
-		return "", errors.Newf(err, "failed to get tag %s for machine with id %s", tagKey, machineID)
-	}
-	return string(resp), nil
+// RemoveMachineTags removes tags from a machine; tags the machine does not have are ignored.
+// It returns the new tags of the machine.
+func (c *Client) RemoveMachineTags(machineID string, tags ...string) ([]string, error) {
+	return c.modifyMachineTags(machineID, func(current map[string]bool) {
+		for _, tag := range tags {
+			delete(current, tag)
+		}
+	})
 }
 
-// DeleteMachineTag deletes a single tag from the specified machine.
-// See API docs: http://apidocs.joyent.com/cloudapi/#DeleteMachineTag
-func (c *Client) DeleteMachineTag(machineID, tagKey string) error {
-	req := request{
-		method:         client.DELETE,
-		url:            makeURL(apiMachines, machineID, apiTags, tagKey),
-		expectedStatus: http.StatusNoContent,
+// ReplaceMachineTags replaces all tags of a machine.
+func (c *Client) ReplaceMachineTags(machineID string, tags []string) ([]string, error) {
+	if tags == nil {
+		tags = []string{}
 	}
-	if _, err := c.sendRequest(req); err != nil {
-		return errors.Newf(err, "failed to delete tag with key %s for machine with id %s", tagKey, machineID)
+	definition, err := c.UpdateMachineDefinition(machineID, &machineTagsChange{Tags: tags})
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return definition.Tags, nil
 }
 
-// DeleteMachineTags deletes all tags from the specified machine.
-// See API docs: http://apidocs.joyent.com/cloudapi/#DeleteMachineTags
+// DeleteMachineTags removes all tags from a machine.
 func (c *Client) DeleteMachineTags(machineID string) error {
-	req := request{
-		method:         client.DELETE,
-		url:            makeURL(apiMachines, machineID, apiTags),
-		expectedStatus: http.StatusNoContent,
+	_, err := c.ReplaceMachineTags(machineID, nil)
+	return err
+}
+
+// Helper that changes the current tags of a machine. The tags are read and written
+// in two calls, so concurrent changes of the same machine may get lost.
+func (c *Client) modifyMachineTags(machineID string, change func(current map[string]bool)) ([]string, error) {
+	tags, err := c.ListMachineTags(machineID)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		current[tag] = true
 	}
-	if _, err := c.sendRequest(req); err != nil {
-		return errors.Newf(err, "failed to delete tags for machine with id %s", machineID)
+	change(current)
+
+	newTags := make([]string, 0, len(current))
+	for tag := range current {
+		newTags = append(newTags, tag)
 	}
-	return nil
+	sort.Strings(newTags)
+	return c.ReplaceMachineTags(machineID, newTags)
 }
@@ -4,8 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
-	"time"
 
 	"github.com/erigones/godanube/client"
 	"github.com/erigones/godanube/errors"
@@ -22,6 +20,12 @@ const (
 
     VmDeployTimeout = 300 / TaskQuerySleepTime   // seconds
 	VmDeleteTimeout = 100 / TaskQuerySleepTime
+	VmStatusTimeout = 100 / TaskQuerySleepTime
+
+	// machine status actions
+	VmActionStart  = "start"
+	VmActionStop   = "stop"
+	VmActionReboot = "reboot"
 )
 
 // Machine represent a provisioned virtual machines
@@ -52,7 +56,8 @@ type VmDetails struct { // "result": [
 	Changed						bool
 }
 
-//DELME
+//DELME Joyent machine, returned only by the Joyent firewall API (ListFirewallRuleMachines) and used by the local double;
+// Danube machines are MachineDetails (GetMachineDetails) or VmDetails (GetMachine, QueryMachines)
 type Machine struct {
 	Id              string            // Unique identifier for the image
 	Name            string            // Machine friendly name
@@ -108,12 +113,12 @@ type MachineDefinition struct {
 	Mdata           map[string]string `json:"mdata,omitempty"`
 
     // Not settable, only for querying:
-    Uuid            string
-    Resolvers       []string
-    Locked          bool
-    Created         string
-    Changed         bool
-    Cpu_shares      int
+    Uuid            string          `json:"uuid,omitempty"`
+    Resolvers       []string        `json:"resolvers,omitempty"`
+    Locked          bool            `json:"locked,omitempty"`
+    Created         string          `json:"created,omitempty"`
+    Changed         bool            `json:"changed,omitempty"`
+    Cpu_shares      int             `json:"-"` // DELME duplicate of CpuShares
 }
 
 type VmNicDefinition struct {
@@ -139,6 +144,9 @@ type VmNicDefinition struct {
 
     // Not settable, only for querying:
 	Netmask                  string        `json:"netmask,omitempty"`
+
+	// note of the IP reservation made by ReserveNextFreeIp() for this NIC
+	reservation string
 }
 
 type VmDiskDefinition struct {
@@ -313,54 +321,13 @@ func (c *Client) ListMachines() ([]string, error) {
 	return resp.Result, nil
 }
 
+// ListMachinesFilteredFull returns details of machines matching all set fields of vmfilter:
+// hostname, alias and node by substring, uuid, owner and status exactly, and all the tags.
+// Use QueryMachines() for ordering, paging or other conditions.
 func (c *Client) ListMachinesFilteredFull(vmfilter VmDetails) ([]VmDetails, error) {
-//J
-	var resp VmsResponse
-	filter := NewFilter()
-	filter.Set("extended", "true")
-	req := request{
-		method: client.GET,
-		url:    "vm",
-		filter:	filter,
-		resp:   &resp,
-	}
-	if _, err := c.sendRequest(req); err != nil {
-		return nil, errors.Newf2(err, resp.Detail, "failed to get list of machines")
-	}
-
-	allVMs := resp.Result
-	var vmListFiltered []VmDetails
-
-	// iterate over tags to filter VMs
-	for _, vm := range allVMs {
-		if
-		(vmfilter.Hostname != "" && strings.Contains(vm.Hostname, vmfilter.Hostname)) ||
-		(vmfilter.Uuid != "" && vmfilter.Uuid == vm.Uuid) ||
-		(vmfilter.Alias != "" && strings.Contains(vm.Alias, vmfilter.Alias)) ||
-		(vmfilter.Node != "" && strings.Contains(vm.Node, vmfilter.Node)) ||
-		(vmfilter.Owner != "" && vmfilter.Owner == vm.Owner) ||
-		(vmfilter.Status != "" && vmfilter.Status == vm.Status) {
-			vmListFiltered = append(vmListFiltered, vm)
-
-		} else if vmfilter.Tags != nil {
-			someTagNotFound := false
-			for _, tag := range vmfilter.Tags {
-				found := false
-				for _, vmtag := range vm.Tags {
-					if vmtag == tag {
-						found = true
-						break
-					}
-				}
-				if !found {
-					someTagNotFound = true
-					break
-				}
-			}
-			if !someTagNotFound {
-				vmListFiltered = append(vmListFiltered, vm)
-			}
-		}
+	vmListFiltered, err := c.QueryMachines(MachineQueryFromDetails(vmfilter))
+	if err != nil {
+		return nil, err
 	}
 
 	if c.client.GetTrace() {
@@ -451,10 +418,6 @@ func (c *Client) GetMachineState(machineID string) (*string, error) {
 	return &resp.Result.Status, nil
 }
 
-func isTransientState(state string) bool {
-	return strings.HasSuffix(state, "-")
-}
-
 func (c *Client) GetMachineNics(machineId string) ([]VmNicDefinition, error) {
 //J
 	var resp VmNicsResponse
@@ -501,6 +464,64 @@ func (c *Client) CreateMachineDefinition(opts MachineDefinition) (*MachineDefini
 	return &resp.Result, nil
 }
 
+// GetMachineDefinition returns the definition (configuration) of a machine.
+func (c *Client) GetMachineDefinition(machineID string) (*MachineDefinition, error) {
+	var resp CreateMachineResponse
+	req := request{
+		method: client.GET,
+		url:    makeURL("vm", machineID, "define"),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get definition of machine \"%s\"", machineID)
+	}
+	return &resp.Result, nil
+}
+
+// UpdateMachineDefinition changes the attributes of a machine definition set in changes
+// (a pointer to a struct embedding ReqData with omitempty fields, so that only the changed
+// attributes are sent) and applies the new definition to a deployed machine (see ApplyMachineChanges()).
+// If the machine already had pending changes, the definition is only saved, because applying
+// it would also apply those changes; it is up to the caller to call ApplyMachineChanges().
+func (c *Client) UpdateMachineDefinition(machineID string, changes interface{}) (*MachineDefinition, error) {
+	vm, err := c.GetMachine(machineID)
+	if err != nil {
+		return nil, err
+	}
+	definition, err := c.updateMachineDefinition(machineID, changes)
+	if err != nil {
+		return nil, err
+	}
+	if definition.Changed && !vm.Changed {
+		state, err := c.GetMachineVmState(machineID)
+		if err != nil {
+			return nil, err
+		}
+		if !state.CanDeploy() {
+			if err := c.ApplyMachineChanges(machineID); err != nil {
+				return nil, err
+			}
+			definition.Changed = false
+		}
+	}
+	return definition, nil
+}
+
+// Helper that changes a machine definition without applying it.
+func (c *Client) updateMachineDefinition(machineID string, changes interface{}) (*MachineDefinition, error) {
+	var resp CreateMachineResponse
+	req := request{
+		method:   client.PUT,
+		url:      makeURL("vm", machineID, "define"),
+		reqValue: changes,
+		resp:     &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to update definition of machine \"%s\"", machineID)
+	}
+	return &resp.Result, nil
+}
+
 func (c *Client) AddMachineNicDefinition(machineID string, opts VmNicDefinition) (*VmNicDefinition, error) {
     errStr := "failed to create nic definition for machine: %s"
     nics, nicErr := c.GetMachineNics(machineID)
@@ -509,6 +530,13 @@ func (c *Client) AddMachineNicDefinition(machineID string, opts VmNicDefinition)
 	}
     nicCount := len(nics)
 
+	// Danube assigns only unreserved addresses, so the reservation made by ReserveNextFreeIp()
+	// is removed right before the NIC is defined and put back if the definition fails
+	released, err := c.releaseNicIpReservation(opts.Net, opts.Ip, opts.reservation)
+	if err != nil {
+		return nil, errors.Newf(err, errStr, machineID)
+	}
+
 	var resp VmNicResponse
 
 	req := request{
@@ -520,6 +548,11 @@ func (c *Client) AddMachineNicDefinition(machineID string, opts VmNicDefinition)
 		expectedStatus: http.StatusCreated,
 	}
 	if _, err := c.sendRequest(req); err != nil {
+		if released {
+			if _, rerr := c.ReserveNetworkIp(opts.Net, opts.Ip, opts.reservation); rerr != nil {
+				return nil, errors.Newf(err, errStr+" (and failed to restore reservation of IP address %s: %v)", machineID, opts.Ip, rerr)
+			}
+		}
 		return nil, errors.Newf(err, errStr, machineID)
 	}
 	return &resp.Result, nil
@@ -551,6 +584,10 @@ func (c *Client) AddMachineDiskDefinition(machineID string, opts VmDiskDefinitio
 
 // DeployMachine actualy deploys VM on a compute node
 func (c *Client) DeployMachine(machineID string) (error) {
+	if _, err := c.waitForTransition(machineID, VmStateDeploying, VmDeployTimeout); err != nil {
+		return errors.Newf(err, "failed to deploy machine \"%s\"", machineID)
+	}
+
 	var resp DcResponse
 	req := request{
 		method:         client.POST,
@@ -625,97 +662,181 @@ func (c *Client) CreateMachine(definition CreateMachineOpts) (*MachineDefinition
 	return machine, nil
 }
 
-// StopMachine stops a running machine.
-// See API docs: http://apidocs.joyent.com/cloudapi/#StopMachine
+// StopMachineOpts represent the options that can be specified when stopping a machine.
+type StopMachineOpts struct {
+	ReqData       // Force stops the machine immediately (like pulling the power cord)
+	Timeout  int  `json:"timeout,omitempty"`  // seconds to wait for a graceful shutdown before the machine is stopped forcefully
+	Freeze   bool `json:"freeze,omitempty"`   // keep the machine stopped (frozen) until it is unfrozen
+	Unfreeze bool `json:"unfreeze,omitempty"` // unfreeze a frozen machine (it stays stopped)
+}
+
+// StartMachineOpts represent the options that can be specified when starting a machine.
+type StartMachineOpts struct {
+	ReqData
+	Unfreeze bool `json:"unfreeze,omitempty"` // unfreeze and start a frozen machine
+	NoUpdate bool `json:"-"`                  // do not apply pending definition changes before the start
+}
+
+// MarshalJSON sends NoUpdate as the API parameter update (which is true by default).
+func (o StartMachineOpts) MarshalJSON() ([]byte, error) {
+	type plain StartMachineOpts
+	return json.Marshal(struct {
+		plain
+		Update bool `json:"update"`
+	}{plain(o), !o.NoUpdate})
+}
+
+// RebootMachineOpts represent the options that can be specified when rebooting a machine.
+type RebootMachineOpts struct {
+	ReqData     // Force reboots the machine immediately (like pressing the reset button)
+	Timeout int `json:"timeout,omitempty"` // seconds to wait for a graceful shutdown before the machine is rebooted forcefully
+}
+
+// StopMachine stops a running machine. It does nothing if the machine is already stopped.
 func (c *Client) StopMachine(machineID string, force bool) error {
-//J
-	if state, err := c.GetMachineState(machineID); err == nil && *state == "stopped" {
+	if state, err := c.GetMachineVmState(machineID); err == nil && state.IsStopped() {
 		return nil
 	}
-	var resp DcResponse
-	var opts ReqData
+	opts := StopMachineOpts{}
 	opts.Force = force
-	req := request{
-		method:         client.PUT,
-		url:            fmt.Sprintf("vm/%s/status/stop/", machineID),
-		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
-		reqValue:		&opts,
-		resp:           &resp,
-	}
-	if _, err := c.sendRequest(req); err != nil {
-		return errors.Newf2(err, resp.Detail, "failed to stop machine with id: %s", machineID)
-	}
+	return c.StopMachineWithOpts(machineID, opts)
+}
 
-	_, err := c.WaitForTaskStatus(resp.Task_id, "SUCCESS", VmDeleteTimeout, req.expectedStatuses)
-	if err != nil {
+// StopMachineWithOpts stops a running machine with the given options.
+func (c *Client) StopMachineWithOpts(machineID string, opts StopMachineOpts) error {
+	if err := c.changeMachineStatus(machineID, VmActionStop, &opts, VmStatusTimeout+uint(opts.Timeout)/TaskQuerySleepTime); err != nil {
 		return errors.Newf(err, "failed to stop machine \"%s\"", machineID)
 	}
-
 	return nil
 }
 
-// StartMachine starts a stopped machine.
-// See API docs: http://apidocs.joyent.com/cloudapi/#StartMachine
+// StartMachine starts a stopped machine. It does nothing if the machine is already running.
+// Pending definition changes are applied before the start.
 func (c *Client) StartMachine(machineID string) error {
-	var resp DcResponse
-	req := request{
-		method:         client.POST,
-		url:            fmt.Sprintf("%s/%s?action=%s", apiMachines, machineID, actionStart),
-		expectedStatus: http.StatusAccepted,
-		resp:           &resp,
-	}
-	if _, err := c.sendRequest(req); err != nil {
-		return errors.Newf2(err, resp.Detail, "failed to start machine with id: %s", machineID)
+	if state, err := c.GetMachineVmState(machineID); err == nil && state.IsRunning() {
+		return nil
 	}
+	return c.StartMachineWithOpts(machineID, StartMachineOpts{})
+}
 
-	_, err := c.WaitForTaskStatus(resp.Task_id, "SUCCESS", VmDeleteTimeout, req.expectedStatuses)
-	if err != nil {
-		return errors.Newf(err, "failed to stop machine \"%s\"", machineID)
+// StartMachineWithOpts starts a stopped machine with the given options.
+func (c *Client) StartMachineWithOpts(machineID string, opts StartMachineOpts) error {
+	if err := c.changeMachineStatus(machineID, VmActionStart, &opts, VmStatusTimeout); err != nil {
+		return errors.Newf(err, "failed to start machine \"%s\"", machineID)
 	}
+	return nil
+}
+
+// StartMachineIsoOpts represent the option that can be specified
+// when starting a KVM machine with ISO images in its CD-ROM drives.
+type StartMachineIsoOpts struct {
+	ReqData
+	Cdimage  string `json:"cdimage"`            // ISO image in the first CD-ROM drive (the machine boots from it)
+	Cdimage2 string `json:"cdimage2,omitempty"` // ISO image in the second CD-ROM drive (e.g. drivers)
+	KeepIso  bool   `json:"-"`                  // boot from the ISO on every start, not only on this one
+}
+
+// MarshalJSON sends KeepIso as the API parameter cdimage_once (which is true by default).
+func (o StartMachineIsoOpts) MarshalJSON() ([]byte, error) {
+	type plain StartMachineIsoOpts
+	return json.Marshal(struct {
+		plain
+		CdimageOnce bool `json:"cdimage_once"`
+	}{plain(o), !o.KeepIso})
+}
 
+// StartMachineWithIso starts a stopped KVM machine with ISO images inserted,
+// e.g. to install an operating system or an appliance.
+func (c *Client) StartMachineWithIso(machineID string, opts StartMachineIsoOpts) error {
+	if opts.Cdimage == "" {
+		return errors.NewMissingParameterf(nil, "", "no ISO image to start machine \"%s\" with", machineID)
+	}
+	if err := c.changeMachineStatus(machineID, VmActionStart, &opts, VmStatusTimeout); err != nil {
+		return errors.Newf(err, "failed to start machine \"%s\" with ISO image \"%s\"", machineID, opts.Cdimage)
+	}
 	return nil
 }
 
-// RebootMachine reboots (stop followed by a start) a machine.
-// See API docs: http://apidocs.joyent.com/cloudapi/#RebootMachine
+// RebootMachine gracefully reboots a running machine.
 func (c *Client) RebootMachine(machineID string) error {
-	req := request{
-		method:         client.POST,
-		url:            fmt.Sprintf("%s/%s?action=%s", apiMachines, machineID, actionReboot),
-		expectedStatus: http.StatusAccepted,
-	}
-	if _, err := c.sendRequest(req); err != nil {
-		return errors.Newf(err, "failed to reboot machine with id: %s", machineID)
+	return c.RebootMachineWithOpts(machineID, RebootMachineOpts{})
+}
+
+// RebootMachineWithOpts reboots a running machine with the given options.
+func (c *Client) RebootMachineWithOpts(machineID string, opts RebootMachineOpts) error {
+	if err := c.changeMachineStatus(machineID, VmActionReboot, &opts, VmStatusTimeout+uint(opts.Timeout)/TaskQuerySleepTime); err != nil {
+		return errors.Newf(err, "failed to reboot machine \"%s\"", machineID)
 	}
 	return nil
 }
 
-// ResizeMachine allows you to resize a SmartMachine. Virtual machines can also
-// be resized, but only resizing virtual machines to a higher capacity package
-// is supported.
-// See API docs: http://apidocs.joyent.com/cloudapi/#ResizeMachine
-func (c *Client) ResizeMachine(machineID, packageName string) error {
+// FreezeMachine stops a machine and keeps it stopped: a frozen machine
+// cannot be started until UnfreezeMachine() is called.
+func (c *Client) FreezeMachine(machineID string, force bool) error {
+	opts := StopMachineOpts{Freeze: true}
+	opts.Force = force
+	return c.StopMachineWithOpts(machineID, opts)
+}
+
+// UnfreezeMachine unfreezes a frozen machine. The machine is started if start is true,
+// otherwise it stays stopped.
+func (c *Client) UnfreezeMachine(machineID string, start bool) error {
+	if start {
+		return c.StartMachineWithOpts(machineID, StartMachineOpts{Unfreeze: true})
+	}
+	return c.StopMachineWithOpts(machineID, StopMachineOpts{Unfreeze: true})
+}
+
+// vmActionStates maps status actions (VmAction*) to the states they lead to
+var vmActionStates = map[string]VmState{
+	VmActionStart:  VmStateRunning,
+	VmActionStop:   VmStateStopped,
+	VmActionReboot: VmStateRunning,
+}
+
+// Helper that runs a status action (VmAction*) and waits for the task it starts.
+func (c *Client) changeMachineStatus(machineID, action string, opts interface{}, timeoutSec uint) error {
+	to := vmActionStates[action]
+	if o, ok := opts.(*StopMachineOpts); ok && o.Freeze {
+		to = VmStateFrozen
+	}
+	if _, err := c.waitForTransition(machineID, to, VmStatusTimeout); err != nil {
+		return errors.Newf(err, "%s action failed", action)
+	}
+
+	var resp DcResponse
 	req := request{
-		method:         client.POST,
-		url:            fmt.Sprintf("%s/%s?action=%s&package=%s", apiMachines, machineID, actionResize, packageName),
-		expectedStatus: http.StatusAccepted,
+		method:           client.PUT,
+		url:              makeURL("vm", machineID, "status", action),
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+		reqValue:         opts,
+		resp:             &resp,
 	}
 	if _, err := c.sendRequest(req); err != nil {
-		return errors.Newf(err, "failed to resize machine with id: %s", machineID)
+		return errors.Newf2(err, resp.Detail, "%s action failed", action)
+	}
+	if err := c.waitForOptionalTask(&resp, timeoutSec, req.expectedStatuses); err != nil {
+		return errors.Newf2(err, resp.Detail, "%s action failed", action)
 	}
 	return nil
 }
 
-// RenameMachine renames an existing machine.
-// See API docs: http://apidocs.joyent.com/cloudapi/#RenameMachine
+// RenameMachine changes the alias (DNS name without a domain) of a machine.
+// The new alias is applied on the next update or start of the machine.
 func (c *Client) RenameMachine(machineID, machineName string) error {
+	var resp DcResponse
+	opts := struct {
+		ReqData
+		Alias string `json:"alias"`
+	}{Alias: machineName}
 	req := request{
-		method:         client.POST,
-		url:            fmt.Sprintf("%s/%s?action=%s&name=%s", apiMachines, machineID, actionRename, machineName),
-		expectedStatus: http.StatusAccepted,
+		method:   client.PUT,
+		url:      makeURL("vm", machineID, "define"),
+		reqValue: &opts,
+		resp:     &resp,
 	}
 	if _, err := c.sendRequest(req); err != nil {
-		return errors.Newf(err, "failed to rename machine with id: %s", machineID)
+		return errors.Newf2(err, resp.Detail, "failed to rename machine \"%s\" to \"%s\"", machineID, machineName)
 	}
 	return nil
 }
@@ -724,6 +845,10 @@ func (c *Client) RenameMachine(machineID, machineName string) error {
 // Use DeleteMachineDefinition() for complete removal.
 func (c *Client) DestroyMachine(machineID string) error {
 //J
+	if _, err := c.waitForTransition(machineID, VmStateNotCreated, VmDeleteTimeout); err != nil {
+		return errors.Newf(err, "failed to delete machine \"%s\"", machineID)
+	}
+
 	var resp DcResponse
 	req := request{
 		method:         client.DELETE,
@@ -765,41 +890,26 @@ func (c *Client) DeleteMachineDefinition(machineID string) error {
 func (c *Client) DeleteMachine(machineID string, force bool) error {
 	errMsg := "failed to delete machine: " + machineID
 
-	var status string
-	for timeout := VmDeployTimeout; timeout > 0; timeout-=1 {
-		vmStatus, err := c.GetMachineState(machineID)
-		if err != nil {
-			return errors.Newf(err, errMsg)
-		}
-		status = *vmStatus
-
-		if
-		status == "deploying" || status == "notready" ||
-		status == "starting" || status == "stopping" ||
-		strings.HasSuffix(status, "-") {
-			// transient state, wait for finish
-			time.Sleep(TaskQuerySleepTime * time.Second)
-			continue
-		} else {
-			break
-		}
+	state, err := c.waitForStableState(machineID, VmDeployTimeout)
+	if err != nil {
+		return errors.Newf(err, errMsg)
 	}
 
 	stop := false
 	destroy := false
 	del := false
 
-	if status == "running" {
+	if state.IsRunning() {
 		stop = true
 		destroy = true
 		del = true
-	} else if status == "stopped" {
+	} else if state.CanDestroy() {
 		destroy = true
 		del = true
-	} else if status == "notcreated" {
+	} else if state.CanDeploy() {
 		del = true
 	} else {
-		return fmt.Errorf("Cannot delete machine \"%s\": invalid machine state", machineID)
+		return fmt.Errorf("Cannot delete machine \"%s\": invalid machine state \"%s\"", machineID, state)
 	}
 
 	if stop == true {
@@ -0,0 +1,249 @@
+package cloudapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/erigones/godanube/client"
+	"github.com/erigones/godanube/errors"
+)
+
+const (
+	monTaskTimeout = 60 / TaskQuerySleepTime
+
+	// alert severities (Zabbix trigger priorities)
+	MonSeverityNotClassified = 0
+	MonSeverityInformation   = 1
+	MonSeverityWarning       = 2
+	MonSeverityAverage       = 3
+	MonSeverityHigh          = 4
+	MonSeverityDisaster      = 5
+)
+
+// MonTemplate is a monitoring template that can be used in MachineDefinition.MonitoringTemplates
+type MonTemplate struct {
+	Id          int    `json:"id,omitempty"`
+	Name        string `json:"name"`
+	VisibleName string `json:"visible_name,omitempty"`
+	Desc        string `json:"desc,omitempty"`
+	DcBound     bool   `json:"dc_bound,omitempty"` // whether the template belongs to the current vDC only
+}
+
+// MonHostgroup is a monitoring hostgroup that can be used in MachineDefinition.MonitoringHostgroups
+type MonHostgroup struct {
+	Id      int    `json:"id,omitempty"`
+	Name    string `json:"name"`
+	DcBound bool   `json:"dc_bound,omitempty"` // whether the hostgroup belongs to the current vDC only
+}
+
+// MonAction defines who gets notified about alerts in the given hostgroups
+// https://docs.danubecloud.org/api-reference/api/mon_action.html
+type MonAction struct {
+	ReqData
+	Name                   string   `json:"name,omitempty"`
+	Enabled                bool     `json:"enabled"`
+	Hostgroups             []string `json:"hostgroups"` // alerts of hosts in these hostgroups trigger the action
+	Usergroups             []string `json:"usergroups"` // members of these user groups are notified
+	MessageSubject         string   `json:"message_subject,omitempty"`
+	MessageText            string   `json:"message_text,omitempty"`
+	RecoveryMessageEnabled bool     `json:"recovery_message_enabled"`
+	RecoveryMessageSubject string   `json:"recovery_message_subject,omitempty"`
+	RecoveryMessageText    string   `json:"recovery_message_text,omitempty"`
+	DcBound                bool     `json:"dc_bound,omitempty"`
+}
+
+// MonAlert is a problem reported by the monitoring system
+type MonAlert struct {
+	EventId      int    `json:"eventid"`
+	Priority     int    `json:"priority"` // severity, one of MonSeverity*
+	Hostname     string `json:"hostname"` // hostname of the VM or compute node
+	Desc         string `json:"desc"`
+	LatestData   string `json:"latest_data,omitempty"`
+	LastChange   int64  `json:"last_change,omitempty"` // unix timestamp of the last state change
+	Acknowledged bool   `json:"acknowledged,omitempty"`
+}
+
+// MonAlertFilter represents the filters that can be specified when querying alerts.
+// Empty values match everything.
+type MonAlertFilter struct {
+	VmHostnames   []string  // only alerts of these VMs
+	NodeHostnames []string  // only alerts of these compute nodes
+	MinSeverity   int       // only alerts with at least this severity (MonSeverity*)
+	Since         time.Time // only alerts that changed at or after this time
+	Until         time.Time // only alerts that changed before this time
+	Last          int       // return at most this many latest alerts
+	ShowAll       bool      // include alerts of hosts from all vDCs (SuperAdmin only)
+}
+
+/*** STRUCTS FOR MONITORING-SPECIFIC DC RESPONSES ***/
+type MonActionResponse struct {
+	DcResponse
+	Result MonAction `json:"result"`
+}
+
+type MonActionResponseFull struct {
+	DcResponse
+	Result []MonAction `json:"result"`
+}
+
+// ListMonTemplates returns monitoring templates available in the current vDC.
+func (c *Client) ListMonTemplates() ([]MonTemplate, error) {
+	var templates []MonTemplate
+	if err := c.getMonResult(makeURL("mon", "template"), nil, &templates); err != nil {
+		return nil, errors.Newf(err, "failed to get list of monitoring templates")
+	}
+	return templates, nil
+}
+
+// ListMonHostgroups returns monitoring hostgroups available in the current vDC.
+func (c *Client) ListMonHostgroups() ([]MonHostgroup, error) {
+	var hostgroups []MonHostgroup
+	if err := c.getMonResult(makeURL("mon", "hostgroup"), nil, &hostgroups); err != nil {
+		return nil, errors.Newf(err, "failed to get list of monitoring hostgroups")
+	}
+	return hostgroups, nil
+}
+
+// ListMonActions returns names of monitoring actions in the current vDC.
+func (c *Client) ListMonActions() ([]string, error) {
+	var resp ResponseList
+	req := request{
+		method: client.GET,
+		url:    makeURL("mon", "action"),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of monitoring actions")
+	}
+	return resp.Result, nil
+}
+
+// ListMonActionsFull returns details of monitoring actions in the current vDC.
+func (c *Client) ListMonActionsFull() ([]MonAction, error) {
+	var actions []MonAction
+	filter := NewFilter()
+	filter.Set("full", "true")
+	if err := c.getMonResult(makeURL("mon", "action"), filter, &actions); err != nil {
+		return nil, errors.Newf(err, "failed to get list of monitoring actions")
+	}
+	return actions, nil
+}
+
+// GetMonAction returns details of a monitoring action.
+func (c *Client) GetMonAction(actionName string) (*MonAction, error) {
+	var action MonAction
+	if err := c.getMonResult(makeURL("mon", "action", actionName), nil, &action); err != nil {
+		return nil, errors.Newf(err, "failed to get monitoring action \"%s\"", actionName)
+	}
+	return &action, nil
+}
+
+// CreateMonAction creates a new monitoring action.
+func (c *Client) CreateMonAction(opts MonAction) error {
+	return c.modifyMonAction(client.POST, opts, "create")
+}
+
+// UpdateMonAction changes a monitoring action. Hostgroups and usergroups are replaced
+// as a whole, so start from the action returned by GetMonAction().
+func (c *Client) UpdateMonAction(opts MonAction) error {
+	return c.modifyMonAction(client.PUT, opts, "update")
+}
+
+// DeleteMonAction deletes a monitoring action.
+func (c *Client) DeleteMonAction(actionName string) error {
+	return c.modifyMonAction(client.DELETE, MonAction{Name: actionName}, "delete")
+}
+
+// ListMonAlerts returns current monitoring alerts matching the filter.
+// VM, node and time filters are applied by the server, the severity filter by the client.
+func (c *Client) ListMonAlerts(opts MonAlertFilter) ([]MonAlert, error) {
+	filter := NewFilter()
+	for _, hostname := range opts.VmHostnames {
+		filter.Add("vm_hostnames", hostname)
+	}
+	for _, hostname := range opts.NodeHostnames {
+		filter.Add("node_hostnames", hostname)
+	}
+	if !opts.Since.IsZero() {
+		filter.Set("since", strconv.FormatInt(opts.Since.Unix(), 10))
+	}
+	if !opts.Until.IsZero() {
+		filter.Set("until", strconv.FormatInt(opts.Until.Unix(), 10))
+	}
+	if opts.Last > 0 {
+		filter.Set("last", strconv.Itoa(opts.Last))
+	}
+	if opts.ShowAll {
+		filter.Set("show_all", "true")
+	}
+
+	var alerts []MonAlert
+	if err := c.getMonResult(makeURL("mon", "alert"), filter, &alerts); err != nil {
+		return nil, errors.Newf(err, "failed to get list of monitoring alerts")
+	}
+	if opts.MinSeverity <= MonSeverityNotClassified {
+		return alerts, nil
+	}
+	found := alerts[:0]
+	for _, alert := range alerts {
+		if alert.Priority >= opts.MinSeverity {
+			found = append(found, alert)
+		}
+	}
+	return found, nil
+}
+
+// Helper that reads data from the monitoring API. The data is returned either
+// directly (when cached by the server) or as a result of a task.
+func (c *Client) getMonResult(url string, filter *Filter, result interface{}) error {
+	var resp struct {
+		DcResponse
+		Result json.RawMessage `json:"result"`
+	}
+	req := request{
+		method:           client.GET,
+		url:              url,
+		filter:           filter,
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return errors.Newf2(err, resp.Detail, "request failed")
+	}
+	if resp.Status != "SUCCESS" && resp.Task_id != "" {
+		return c.waitForTaskResult(resp.Task_id, monTaskTimeout, result)
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return errors.Newf(err, "failed to decode response")
+	}
+	return nil
+}
+
+// Helper that creates, updates or deletes a monitoring action and waits for the change.
+func (c *Client) modifyMonAction(method string, opts MonAction, what string) error {
+	if opts.Hostgroups == nil {
+		opts.Hostgroups = []string{}
+	}
+	if opts.Usergroups == nil {
+		opts.Usergroups = []string{}
+	}
+	var resp DcResponse
+	req := request{
+		method:           method,
+		url:              makeURL("mon", "action", opts.Name),
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+	}
+	if method != client.DELETE {
+		req.reqValue = &opts
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return errors.Newf2(err, resp.Detail, "failed to %s monitoring action \"%s\"", what, opts.Name)
+	}
+	if err := c.waitForOptionalTask(&resp, monTaskTimeout, req.expectedStatuses); err != nil {
+		return errors.Newf2(err, resp.Detail, "failed to %s monitoring action \"%s\"", what, opts.Name)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package cloudapi
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"sort"
+
+	"github.com/erigones/godanube/errors"
+)
+
+const (
+	// prefix of the note of IP addresses reserved by ReserveNextFreeIp();
+	// the note ends with a token unique to the reservation
+	NicIpReservationNote = "godanube: reserved for NIC definition"
+
+	defaultIpReserveAttempts = 5
+)
+
+// NextFreeIpOpts represent the constraints that can be specified
+// when looking for a free IP address in a network.
+type NextFreeIpOpts struct {
+	Subnet      string // optional CIDR (e.g. "10.0.0.128/25") the address must belong to
+	RangeStart  string // optional first acceptable address (inclusive)
+	RangeEnd    string // optional last acceptable address (inclusive)
+	MaxAttempts int    // how many addresses to try if the reservation races another client (default 5)
+}
+
+// ReserveNextFreeIp finds the lowest free IP address in a network that matches opts,
+// reserves it on the server and returns a NIC definition with the network and IP set.
+// If another client takes the address first, the next free one is tried.
+//
+// The reservation is removed by AddMachineNicDefinition() called with the returned NIC
+// definition (NIC definitions of other clients with the same IP leave it untouched).
+// Use ReleaseNetworkIp() if the NIC definition is not going to be created.
+func (c *Client) ReserveNextFreeIp(networkName string, opts NextFreeIpOpts) (*VmNicDefinition, error) {
+	errMsg := "failed to reserve free IP address in network \"%s\""
+	inRange, err := opts.matcher()
+	if err != nil {
+		return nil, errors.NewInvalidArgumentf(err, "", errMsg, networkName)
+	}
+	attempts := opts.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultIpReserveAttempts
+	}
+
+	note, err := newReservationNote()
+	if err != nil {
+		return nil, errors.Newf(err, errMsg, networkName)
+	}
+	tried := make(map[string]bool)
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		free, err := c.ListFreeNetworkIps(networkName)
+		if err != nil {
+			return nil, errors.Newf(err, errMsg, networkName)
+		}
+		candidate := lowestIp(free, func(ip net.IP) bool {
+			return !tried[ip.String()] && inRange(ip)
+		})
+		if candidate == nil {
+			break
+		}
+		tried[candidate.String()] = true
+
+		reserved, err := c.ReserveNetworkIp(networkName, candidate.String(), note)
+		if err != nil {
+			// most probably taken by somebody else in the meantime, try the next one
+			lastErr = err
+			continue
+		}
+		return &VmNicDefinition{Net: networkName, Ip: reserved.Ip, reservation: note}, nil
+	}
+
+	if lastErr != nil {
+		return nil, errors.Newf(lastErr, errMsg, networkName)
+	}
+	return nil, errors.Newf(nil, errMsg+": no free address matches the constraints", networkName)
+}
+
+// releaseNicIpReservation removes a reservation made by ReserveNextFreeIp() with the note,
+// so the address can be assigned to a NIC, and tells whether it was removed.
+// Other reservations are left untouched.
+func (c *Client) releaseNicIpReservation(networkName, ip, note string) (bool, error) {
+	if networkName == "" || ip == "" || note == "" {
+		return false, nil
+	}
+	rec, err := c.GetNetworkIp(networkName, ip)
+	if err != nil {
+		if errors.IsResourceNotFound(err) {
+			// the address is not reserved; let the NIC definition decide
+			return false, nil
+		}
+		return false, err
+	}
+	if rec.Usage != IpUsageOther || rec.Note != note {
+		return false, nil
+	}
+	if err := c.ReleaseNetworkIp(networkName, ip); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Helper that returns a reservation note unique to one ReserveNextFreeIp() call.
+func newReservationNote() (string, error) {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return NicIpReservationNote + " " + hex.EncodeToString(token), nil
+}
+
+// Helper that builds a predicate checking the subnet/range constraints.
+func (opts NextFreeIpOpts) matcher() (func(net.IP) bool, error) {
+	var subnet *net.IPNet
+	var start, end net.IP
+	if opts.Subnet != "" {
+		_, ipnet, err := net.ParseCIDR(opts.Subnet)
+		if err != nil {
+			return nil, err
+		}
+		subnet = ipnet
+	}
+	if opts.RangeStart != "" {
+		if start = net.ParseIP(opts.RangeStart); start == nil {
+			return nil, errors.NewInvalidArgumentf(nil, "", "invalid IP address %s", opts.RangeStart)
+		}
+	}
+	if opts.RangeEnd != "" {
+		if end = net.ParseIP(opts.RangeEnd); end == nil {
+			return nil, errors.NewInvalidArgumentf(nil, "", "invalid IP address %s", opts.RangeEnd)
+		}
+	}
+
+	return func(ip net.IP) bool {
+		if subnet != nil && !subnet.Contains(ip) {
+			return false
+		}
+		if start != nil && compareIps(ip, start) < 0 {
+			return false
+		}
+		if end != nil && compareIps(ip, end) > 0 {
+			return false
+		}
+		return true
+	}, nil
+}
+
+// Helper that returns the numerically lowest address from ips accepted by the filter.
+func lowestIp(ips []NetworkIp, accept func(net.IP) bool) net.IP {
+	var parsed []net.IP
+	for _, rec := range ips {
+		if ip := net.ParseIP(rec.Ip); ip != nil && accept(ip) {
+			parsed = append(parsed, ip)
+		}
+	}
+	if len(parsed) == 0 {
+		return nil
+	}
+	sort.Slice(parsed, func(i, j int) bool { return compareIps(parsed[i], parsed[j]) < 0 })
+	return parsed[0]
+}
+
+// compareIps compares two IP addresses numerically.
+func compareIps(a, b net.IP) int {
+	return bytes.Compare(a.To16(), b.To16())
+}
@@ -0,0 +1,191 @@
+package cloudapi
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/erigones/godanube/errors"
+)
+
+// IPNet returns the network address and mask parsed from the Network and Netmask fields.
+// Netmask may be in dotted form ("255.255.255.0") or a prefix length ("24");
+// Network may also be given in CIDR notation.
+func (n *Network) IPNet() (*net.IPNet, error) {
+	if strings.Contains(n.Network, "/") {
+		_, ipnet, err := net.ParseCIDR(n.Network)
+		if err != nil {
+			return nil, fmt.Errorf("network \"%s\": invalid subnet %s", n.Name, n.Network)
+		}
+		return ipnet, nil
+	}
+	ip := net.ParseIP(n.Network)
+	if ip == nil {
+		return nil, fmt.Errorf("network \"%s\": invalid network address %s", n.Name, n.Network)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	var mask net.IPMask
+	if m := net.ParseIP(n.Netmask); m != nil && len(ip) == net.IPv4len {
+		mask = net.IPMask(m.To4())
+		if ones, bits := mask.Size(); ones == 0 && bits == 0 {
+			return nil, fmt.Errorf("network \"%s\": non-contiguous netmask %s", n.Name, n.Netmask)
+		}
+	} else {
+		_, ipnet, err := net.ParseCIDR(n.Network + "/" + strings.TrimPrefix(n.Netmask, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("network \"%s\": invalid netmask %s", n.Name, n.Netmask)
+		}
+		mask = ipnet.Mask
+	}
+
+	return &net.IPNet{IP: ip.Mask(mask), Mask: mask}, nil
+}
+
+// Broadcast returns the broadcast address of an IPv4 network.
+func (n *Network) Broadcast() (net.IP, error) {
+	ipnet, err := n.IPNet()
+	if err != nil {
+		return nil, err
+	}
+	if len(ipnet.IP) != net.IPv4len {
+		return nil, fmt.Errorf("network \"%s\": IPv6 networks have no broadcast address", n.Name)
+	}
+	bcast := make(net.IP, net.IPv4len)
+	for i := range ipnet.IP {
+		bcast[i] = ipnet.IP[i] | ^ipnet.Mask[i]
+	}
+	return bcast, nil
+}
+
+// HostRange returns the first and the last address usable by hosts in the network,
+// i.e. without the network and broadcast addresses (/31 and /32 networks use all addresses).
+func (n *Network) HostRange() (first, last net.IP, err error) {
+	ipnet, err := n.IPNet()
+	if err != nil {
+		return nil, nil, err
+	}
+	first = make(net.IP, len(ipnet.IP))
+	last = make(net.IP, len(ipnet.IP))
+	for i := range ipnet.IP {
+		first[i] = ipnet.IP[i]
+		last[i] = ipnet.IP[i] | ^ipnet.Mask[i]
+	}
+	if ones, bits := ipnet.Mask.Size(); bits-ones > 1 {
+		incIp(first, 1)
+		if len(ipnet.IP) == net.IPv4len {
+			incIp(last, -1) // broadcast
+		}
+	}
+	return first, last, nil
+}
+
+// Contains returns true if ip belongs to the network.
+func (n *Network) Contains(ip string) (bool, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false, fmt.Errorf("invalid IP address %s", ip)
+	}
+	ipnet, err := n.IPNet()
+	if err != nil {
+		return false, err
+	}
+	return ipnet.Contains(addr), nil
+}
+
+// IsUsableHostIp returns true if ip belongs to the network and is not its network
+// or broadcast address.
+func (n *Network) IsUsableHostIp(ip string) (bool, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false, fmt.Errorf("invalid IP address %s", ip)
+	}
+	first, last, err := n.HostRange()
+	if err != nil {
+		return false, err
+	}
+	return compareIps(addr, first) >= 0 && compareIps(addr, last) <= 0, nil
+}
+
+// ValidateGateway checks that the network gateway (if set) is a usable address inside the network.
+func (n *Network) ValidateGateway() error {
+	if n.Gateway == "" {
+		return nil
+	}
+	ok, err := n.IsUsableHostIp(n.Gateway)
+	if err != nil {
+		return errors.NewInvalidArgumentf(err, "", "network \"%s\": invalid gateway", n.Name)
+	}
+	if !ok {
+		ipnet, _ := n.IPNet()
+		return errors.NewInvalidArgumentf(nil, "", "network \"%s\": gateway %s is not usable in %s", n.Name, n.Gateway, ipnet)
+	}
+	return nil
+}
+
+// Validate checks the NIC definition against the network it is going to be connected to.
+// The NIC IP (if set) must be a usable host address of the network other than the gateway
+// and the additional allowed IPs must be valid addresses.
+func (nic *VmNicDefinition) Validate(network *Network) error {
+	if nic.Net != "" && network.Name != "" && nic.Net != network.Name {
+		return errors.NewInvalidArgumentf(nil, "", "NIC is defined for network \"%s\", not \"%s\"", nic.Net, network.Name)
+	}
+	if nic.Ip != "" {
+		ok, err := network.IsUsableHostIp(nic.Ip)
+		if err != nil {
+			return errors.NewInvalidArgumentf(err, "", "invalid NIC IP address %s", nic.Ip)
+		}
+		if !ok {
+			ipnet, _ := network.IPNet()
+			return errors.NewInvalidArgumentf(nil, "", "NIC IP address %s is not usable in network \"%s\" (%s)", nic.Ip, network.Name, ipnet)
+		}
+		if network.Gateway != "" && net.ParseIP(nic.Ip).Equal(net.ParseIP(network.Gateway)) {
+			return errors.NewInvalidArgumentf(nil, "", "NIC IP address %s is the gateway of network \"%s\"", nic.Ip, network.Name)
+		}
+	}
+	for _, ip := range nic.AllowedIps {
+		if net.ParseIP(ip) == nil {
+			if _, _, err := net.ParseCIDR(ip); err != nil {
+				return errors.NewInvalidArgumentf(nil, "", "invalid allowed IP address %s", ip)
+			}
+		}
+	}
+	if nic.Mtu != 0 && network.Mtu != 0 && nic.Mtu > network.Mtu {
+		return errors.NewInvalidArgumentf(nil, "", "NIC MTU %d is larger than MTU %d of network \"%s\"", nic.Mtu, network.Mtu, network.Name)
+	}
+	return nil
+}
+
+// ValidateNicDefinition looks up the target network of the NIC among networks attached
+// to the current vDC and validates the NIC definition against it.
+func (c *Client) ValidateNicDefinition(nic VmNicDefinition) error {
+	networks, err := c.GetAttachedNetworks()
+	if err != nil {
+		return err
+	}
+	for i := range networks {
+		if networks[i].Name == nic.Net {
+			return nic.Validate(&networks[i])
+		}
+	}
+	return errors.NewInvalidArgumentf(nil, "", "network \"%s\" is not attached to virtual datacenter \"%s\"", nic.Net, c.client.GetVirtDC())
+}
+
+// Helper to add a (small) signed number to an IP address in place.
+func incIp(ip net.IP, delta int) {
+	carry := delta
+	for i := len(ip) - 1; i >= 0 && carry != 0; i-- {
+		v := int(ip[i]) + carry
+		ip[i] = byte(v)
+		switch {
+		case v > 255:
+			carry = 1
+		case v < 0:
+			carry = -1
+		default:
+			carry = 0
+		}
+	}
+}
@@ -1,37 +1,100 @@
 package cloudapi
 
 import (
+	"net/http"
+
 	"github.com/erigones/godanube/client"
 	"github.com/erigones/godanube/errors"
 )
 
+const (
+	// usage of an IP address in a network
+	IpUsageVm     = 1 // available for (or used by) VMs
+	IpUsageVmReal = 2 // additional IP address of a VM (e.g. allowed_ips)
+	IpUsageNode   = 3 // used by a compute node
+	IpUsageOther  = 9 // reserved for other (external) use
+)
+
 // Network represents a network available to a given account
 type Network struct {
-    GenericDcEntity
-    Network     string          `json:"network,omitempty`
-    Netmask     string          `json:"netmask,omitempty`
-    Gateway     string          `json:"gateway,omitempty`
-    Nic_tag     string          `json:"nic_tag,omitempty`
-    Nic_tag_type string         `json:"nic_tag_type,omitempty`
-    Vlan_id     int            `json:"vlan_id,omitempty`
-    Vxlan_id    int            `json:"vxlan_id,omitempty`
-    Mtu         int            `json:"mtu,omitempty`
-    Resolvers   []string        `json:"resolvers,omitempty`
-    Dns_domain  string          `json:"dns_domain,omitempty`
-    Ptr_domain  string          `json:"ptr_domain,omitempty`
-    Dhcp_passthrough bool       `json:"dhcp_passthrough,omitempty`
-    Dcs         []string        `json:"dcs,omitempty"`      // vDC list where the network is attached
+	ReqData
+	GenericDcEntity
+	Network          string   `json:"network,omitempty"`
+	Netmask          string   `json:"netmask,omitempty"`
+	Gateway          string   `json:"gateway,omitempty"`
+	Nic_tag          string   `json:"nic_tag,omitempty"`
+	Nic_tag_type     string   `json:"nic_tag_type,omitempty"`
+	Vlan_id          int      `json:"vlan_id,omitempty"`
+	Vxlan_id         int      `json:"vxlan_id,omitempty"`
+	Mtu              int      `json:"mtu,omitempty"`
+	Resolvers        []string `json:"resolvers,omitempty"`
+	Dns_domain       string   `json:"dns_domain,omitempty"`
+	Ptr_domain       string   `json:"ptr_domain,omitempty"`
+	Dhcp_passthrough bool     `json:"dhcp_passthrough,omitempty"`
+	DcBound          bool     `json:"dc_bound,omitempty"` // Whether the network is dedicated to one vDC
+	Dcs              []string `json:"dcs,omitempty"`      // vDC list where the network is attached
+}
+
+// UpdateNetworkOpts represents the attributes changed by UpdateNetwork().
+// Empty strings and nil pointers are left unchanged, so that e.g. Vlan_id: Int(0)
+// makes the network untagged and Resolvers: &[]string{} clears the resolvers.
+type UpdateNetworkOpts struct {
+	ReqData
+	Name             string    `json:"-"` // network to update
+	Alias            string    `json:"alias,omitempty"`
+	Owner            string    `json:"owner,omitempty"`
+	Access           int       `json:"access,omitempty"`
+	Desc             *string   `json:"desc,omitempty"`
+	Network          string    `json:"network,omitempty"`
+	Netmask          string    `json:"netmask,omitempty"`
+	Gateway          *string   `json:"gateway,omitempty"`
+	Nic_tag          string    `json:"nic_tag,omitempty"`
+	Vlan_id          *int      `json:"vlan_id,omitempty"`
+	Vxlan_id         *int      `json:"vxlan_id,omitempty"`
+	Mtu              *int      `json:"mtu,omitempty"`
+	Resolvers        *[]string `json:"resolvers,omitempty"`
+	Dns_domain       *string   `json:"dns_domain,omitempty"`
+	Ptr_domain       *string   `json:"ptr_domain,omitempty"`
+	Dhcp_passthrough *bool     `json:"dhcp_passthrough,omitempty"`
+	DcBound          *bool     `json:"dc_bound,omitempty"`
+}
+
+// NetworkIp represents an IP address record in a network
+type NetworkIp struct {
+	ReqData
+	Ip       string `json:"ip,omitempty"`
+	Net      string `json:"net,omitempty"`      // network name
+	Usage    int    `json:"usage,omitempty"`    // one of IpUsage*
+	Note     string `json:"note,omitempty"`     // free-form note, e.g. owner of a reserved address
+	Mac      string `json:"mac,omitempty"`      // MAC address of the NIC using the IP
+	Hostname string `json:"hostname,omitempty"` // hostname of the VM using the IP
+	Vm       string `json:"vm,omitempty"`       // UUID of the VM using the IP
+}
+
+// IsFree returns true if the IP address is not used by anything and can be assigned to a VM.
+func (ip *NetworkIp) IsFree() bool {
+	return ip.Vm == "" && ip.Hostname == "" && ip.Mac == "" && (ip.Usage == 0 || ip.Usage == IpUsageVm)
 }
 
 /*** STRUCTS FOR NETWORK-SPECIFIC DC RESPONSES ***/
 type NetworkResponse struct {
 	DcResponse
-	Result Network	`json:"result"`
+	Result Network `json:"result"`
 }
 
 type NetworkResponseFull struct {
 	DcResponse
-	Result []Network	`json:"result"`
+	Result []Network `json:"result"`
+}
+
+type NetworkIpResponse struct {
+	DcResponse
+	Result NetworkIp `json:"result"`
+}
+
+type NetworkIpResponseFull struct {
+	DcResponse
+	Result []NetworkIp `json:"result"`
 }
 
 // ListNetworks lists all the networks
@@ -83,3 +146,173 @@ func (c *Client) GetNetwork(networkName string) (*Network, error) {
 	}
 	return &resp.Result, nil
 }
+
+// CreateNetwork creates a new network. Needs SuperAdmin rights.
+// The network has to be attached to a vDC (AttachNetwork()) before it can be used by VMs.
+func (c *Client) CreateNetwork(opts Network) (*Network, error) {
+	var resp NetworkResponse
+	req := request{
+		method:           client.POST,
+		url:              makeURL("network", opts.Name),
+		reqValue:         &opts,
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to create network \"%s\"", opts.Name)
+	}
+	return &resp.Result, nil
+}
+
+// UpdateNetwork changes an existing network (see UpdateNetworkOpts).
+func (c *Client) UpdateNetwork(opts UpdateNetworkOpts) (*Network, error) {
+	var resp NetworkResponse
+	req := request{
+		method:   client.PUT,
+		url:      makeURL("network", opts.Name),
+		reqValue: &opts,
+		resp:     &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to update network \"%s\"", opts.Name)
+	}
+	return &resp.Result, nil
+}
+
+// DeleteNetwork deletes a network. The network must not be used by any VM.
+func (c *Client) DeleteNetwork(networkName string) error {
+	var resp DcResponse
+	req := request{
+		method: client.DELETE,
+		url:    makeURL("network", networkName),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return errors.Newf2(err, resp.Detail, "failed to delete network \"%s\"", networkName)
+	}
+	return nil
+}
+
+// Helper to list IP address records of a network with optional query parameters.
+func (c *Client) listNetworkIps(networkName string, filter *Filter) ([]NetworkIp, error) {
+	var resp NetworkIpResponseFull
+	filter.Set("full", "true")
+	req := request{
+		method: client.GET,
+		url:    makeURL("network", networkName, "ip"),
+		filter: filter,
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get IP addresses of network \"%s\"", networkName)
+	}
+	return resp.Result, nil
+}
+
+// ListNetworkIps returns all IP address records of a network.
+func (c *Client) ListNetworkIps(networkName string) ([]NetworkIp, error) {
+	return c.listNetworkIps(networkName, NewFilter())
+}
+
+// ListUsedNetworkIps returns IP addresses of a network that are used by VMs, nodes or reserved.
+func (c *Client) ListUsedNetworkIps(networkName string) ([]NetworkIp, error) {
+	filter := NewFilter()
+	filter.Set("used", "true")
+	ips, err := c.listNetworkIps(networkName, filter)
+	if err != nil {
+		return nil, err
+	}
+	var used []NetworkIp
+	for _, ip := range ips {
+		if !ip.IsFree() {
+			used = append(used, ip)
+		}
+	}
+	return used, nil
+}
+
+// ListFreeNetworkIps returns IP addresses of a network that can be assigned to a VM.
+func (c *Client) ListFreeNetworkIps(networkName string) ([]NetworkIp, error) {
+	filter := NewFilter()
+	filter.Set("used", "false")
+	ips, err := c.listNetworkIps(networkName, filter)
+	if err != nil {
+		return nil, err
+	}
+	var free []NetworkIp
+	for _, ip := range ips {
+		if ip.IsFree() {
+			free = append(free, ip)
+		}
+	}
+	return free, nil
+}
+
+// GetNetworkIp returns the record of a single IP address in a network.
+func (c *Client) GetNetworkIp(networkName, ip string) (*NetworkIp, error) {
+	var resp NetworkIpResponse
+	req := request{
+		method: client.GET,
+		url:    makeURL("network", networkName, "ip", ip),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get IP address %s in network \"%s\"", ip, networkName)
+	}
+	return &resp.Result, nil
+}
+
+// ReserveNetworkIp marks an IP address in a network as reserved, so it won't be assigned to VMs.
+// Fails if the address is already used or reserved.
+func (c *Client) ReserveNetworkIp(networkName, ip, note string) (*NetworkIp, error) {
+	var resp NetworkIpResponse
+	opts := NetworkIp{Usage: IpUsageOther, Note: note}
+	req := request{
+		method:           client.POST,
+		url:              makeURL("network", networkName, "ip", ip),
+		reqValue:         &opts,
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to reserve IP address %s in network \"%s\"", ip, networkName)
+	}
+	return &resp.Result, nil
+}
+
+// networkIpNote is the request changing the note of an IP address record.
+// The note is always sent, so that it can be cleared with an empty string.
+type networkIpNote struct {
+	ReqData
+	Note string `json:"note"`
+}
+
+// SetNetworkIpNote changes the note of an IP address record (an empty note clears it).
+func (c *Client) SetNetworkIpNote(networkName, ip, note string) (*NetworkIp, error) {
+	var resp NetworkIpResponse
+	opts := networkIpNote{Note: note}
+	req := request{
+		method:   client.PUT,
+		url:      makeURL("network", networkName, "ip", ip),
+		reqValue: &opts,
+		resp:     &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to update IP address %s in network \"%s\"", ip, networkName)
+	}
+	return &resp.Result, nil
+}
+
+// ReleaseNetworkIp removes the reservation of an IP address in a network.
+func (c *Client) ReleaseNetworkIp(networkName, ip string) error {
+	var resp DcResponse
+	req := request{
+		method: client.DELETE,
+		url:    makeURL("network", networkName, "ip", ip),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return errors.Newf2(err, resp.Detail, "failed to release IP address %s in network \"%s\"", ip, networkName)
+	}
+	return nil
+}
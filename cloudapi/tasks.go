@@ -1,6 +1,7 @@
 package cloudapi
 
 import (
+	"encoding/json"
 	"net/http"
 	"fmt"
 	"time"
@@ -148,3 +149,47 @@ func (c *Client) CreateSnap(opts *CreateSnapshotOpts) (*TaskInfo, error) {
 	return taskResult, nil
 }
 
+
+// waitForOptionalTask waits for the task started by an API call to succeed.
+// Some calls finish synchronously and return no task, there is nothing to wait for then.
+func (c *Client) waitForOptionalTask(resp *DcResponse, timeoutSec uint, validHTTPStatuses []int) error {
+	if resp.Task_id == "" {
+		return nil
+	}
+	_, err := c.WaitForTaskStatus(resp.Task_id, "SUCCESS", timeoutSec, validHTTPStatuses)
+	return err
+}
+
+// waitForTaskResult waits for a task to succeed and decodes the task result into result.
+// It is used by API calls that return their data asynchronously (e.g. monitoring calls).
+func (c *Client) waitForTaskResult(taskId string, timeoutSec uint, result interface{}) error {
+	var resp struct {
+		DcResponse
+		Result json.RawMessage `json:"result"`
+	}
+	req := request{
+		method:           client.GET,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+		url:              fmt.Sprintf("%s/%s/status/", "task", taskId),
+		resp:             &resp,
+	}
+	for {
+		if _, err := c.sendRequest(req); err != nil {
+			return errors.Newf2(err, resp.Detail, "failed to get result of task \"%s\"", taskId)
+		}
+		switch resp.Status {
+		case "SUCCESS":
+			if err := json.Unmarshal(resp.Result, result); err != nil {
+				return errors.Newf(err, "failed to decode result of task \"%s\"", taskId)
+			}
+			return nil
+		case "FAILED":
+			return errors.Newf(nil, "Task \"%s\" has failed", taskId)
+		}
+		if timeoutSec <= 0 {
+			return errors.Newf(nil, "Timed out waiting for task \"%s\"", taskId)
+		}
+		timeoutSec--
+		time.Sleep(TaskQuerySleepTime * time.Second)
+	}
+}
@@ -0,0 +1,276 @@
+package cloudapi
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/erigones/godanube/client"
+	"github.com/erigones/godanube/errors"
+)
+
+// Template represents a VM template. The vm_define* sections hold default values
+// applied to machines created with MachineDefinition.Template set to the template name.
+// https://docs.danubecloud.org/api-reference/api/template.html
+type Template struct {
+	ReqData
+	GenericDcEntity                           // contains name, alias, owner, access, desc, etc.
+	OsType           int                      `json:"ostype,omitempty"`   // the template is meant for this ostype
+	DcBound          bool                     `json:"dc_bound,omitempty"` // whether the template is dedicated to one vDC
+	VmDefine         TemplateVmDefine         `json:"vm_define"`
+	VmDefineDisk     []VmDiskDefinition       `json:"vm_define_disk"`
+	VmDefineNic      []VmNicDefinition        `json:"vm_define_nic"`
+	VmDefineSnapshot []TemplateSnapshotDefine `json:"vm_define_snapshot"`
+	VmDefineBackup   []TemplateBackupDefine   `json:"vm_define_backup"`
+}
+
+// TemplateVmDefine holds default VM attributes of a template.
+// Field names and meaning are the same as in MachineDefinition.
+type TemplateVmDefine struct {
+	OsType               int               `json:"ostype,omitempty"`
+	Vcpus                int               `json:"vcpus,omitempty"`
+	Ram                  int               `json:"ram,omitempty"` // in MB
+	Note                 string            `json:"note,omitempty"`
+	DnsDomain            string            `json:"dns_domain,omitempty"`
+	Tags                 []string          `json:"tags,omitempty"`
+	Monitored            bool              `json:"monitored,omitempty"`
+	SnapshotLimitManual  int               `json:"snapshot_limit_manual,omitempty"`
+	SnapshotSizeLimits   int               `json:"snapshot_size_limit,omitempty"`
+	Zpool                string            `json:"zpool,omitempty"`
+	CpuShares            int               `json:"cpu_shares,omitempty"`
+	ZfsIoPriority        int               `json:"zfs_io_priority,omitempty"`
+	CpuType              string            `json:"cpu_type,omitempty"`
+	Vga                  string            `json:"vga,omitempty"`
+	Routes               map[string]string `json:"routes,omitempty"`
+	MonitoringHostgroups []string          `json:"monitoring_hostgroups,omitempty"`
+	MonitoringTemplates  []string          `json:"monitoring_templates,omitempty"`
+	Mdata                map[string]string `json:"mdata,omitempty"`
+}
+
+// TemplateSnapshotDefine is a snapshot schedule created together with a VM from the template
+type TemplateSnapshotDefine struct {
+	Name      string `json:"name"`
+	DiskId    int    `json:"disk_id,omitempty"`
+	Schedule  string `json:"schedule"`  // cron-like schedule
+	Retention int    `json:"retention"` // number of snapshots to keep
+	Desc      string `json:"desc,omitempty"`
+	Active    bool   `json:"active,omitempty"`
+}
+
+// TemplateBackupDefine is a backup schedule created together with a VM from the template
+type TemplateBackupDefine struct {
+	Name        string `json:"name"`
+	DiskId      int    `json:"disk_id,omitempty"`
+	Type        int    `json:"type,omitempty"` // 1 - dataset, 2 - file
+	Node        string `json:"node,omitempty"` // backup node hostname
+	Zpool       string `json:"zpool,omitempty"`
+	Schedule    string `json:"schedule"`  // cron-like schedule
+	Retention   int    `json:"retention"` // number of backups to keep
+	Bwlimit     int    `json:"bwlimit,omitempty"`
+	Compression int    `json:"compression,omitempty"`
+	Desc        string `json:"desc,omitempty"`
+	Active      bool   `json:"active,omitempty"`
+	FsFreeze    bool   `json:"fsfreeze,omitempty"`
+}
+
+/*** STRUCTS FOR TEMPLATE-SPECIFIC DC RESPONSES ***/
+type TemplateResponse struct {
+	DcResponse
+	Result Template `json:"result"`
+}
+
+type TemplateResponseFull struct {
+	DcResponse
+	Result []Template `json:"result"`
+}
+
+// ListTemplates returns names of all VM templates. This call needs SuperAdmin rights.
+// With Admin rights use ListDatacenterTemplates().
+func (c *Client) ListTemplates() ([]string, error) {
+	var resp ResponseList
+	req := request{
+		method: client.GET,
+		url:    "template",
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of templates")
+	}
+	return resp.Result, nil
+}
+
+// ListTemplatesFull returns details of all VM templates. This call needs SuperAdmin rights.
+func (c *Client) ListTemplatesFull() ([]Template, error) {
+	var resp TemplateResponseFull
+	filter := NewFilter()
+	filter.Set("full", "true")
+	req := request{
+		method: client.GET,
+		url:    "template",
+		filter: filter,
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of templates")
+	}
+	return resp.Result, nil
+}
+
+// GetTemplate returns details of a VM template.
+func (c *Client) GetTemplate(templateName string) (*Template, error) {
+	var resp TemplateResponse
+	req := request{
+		method: client.GET,
+		url:    makeURL("template", templateName),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get template \"%s\"", templateName)
+	}
+	return &resp.Result, nil
+}
+
+// CreateTemplate creates a new VM template.
+func (c *Client) CreateTemplate(opts Template) (*Template, error) {
+	opts.setEmptySections()
+	var resp TemplateResponse
+	req := request{
+		method:           client.POST,
+		url:              makeURL("template", opts.Name),
+		reqValue:         &opts,
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to create template \"%s\"", opts.Name)
+	}
+	return &resp.Result, nil
+}
+
+// UpdateTemplate changes a VM template. The vm_define* sections are replaced as a whole,
+// so start from the template returned by GetTemplate().
+func (c *Client) UpdateTemplate(opts Template) (*Template, error) {
+	opts.setEmptySections()
+	var resp TemplateResponse
+	req := request{
+		method:   client.PUT,
+		url:      makeURL("template", opts.Name),
+		reqValue: &opts,
+		resp:     &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to update template \"%s\"", opts.Name)
+	}
+	return &resp.Result, nil
+}
+
+// DeleteTemplate deletes a VM template.
+func (c *Client) DeleteTemplate(templateName string) error {
+	var resp DcResponse
+	req := request{
+		method: client.DELETE,
+		url:    makeURL("template", templateName),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return errors.Newf2(err, resp.Detail, "failed to delete template \"%s\"", templateName)
+	}
+	return nil
+}
+
+// Expand applies the template to a machine definition the same way the server does:
+// values set in opts are kept and unset (zero) values are taken from the template.
+// Template disks and NICs are matched to opts.Disks and opts.Nics by position and
+// extra template disks and NICs are appended. The result shows what a VM created
+// with opts and the template would look like. Snapshot and backup definitions
+// of the template are not part of CreateMachineOpts.
+func (t *Template) Expand(opts CreateMachineOpts) CreateMachineOpts {
+	out := CreateMachineOpts{Vm: opts.Vm}
+	out.Vm.Template = t.Name
+	fillZeroFields(reflect.ValueOf(&out.Vm).Elem(), reflect.ValueOf(t.VmDefine))
+	if out.Vm.OsType == 0 {
+		out.Vm.OsType = t.OsType
+	}
+
+	for i := 0; i < len(opts.Disks) || i < len(t.VmDefineDisk); i++ {
+		var disk VmDiskDefinition
+		if i < len(opts.Disks) {
+			disk = opts.Disks[i]
+		}
+		if i < len(t.VmDefineDisk) {
+			fillZeroFields(reflect.ValueOf(&disk).Elem(), reflect.ValueOf(t.VmDefineDisk[i]))
+		}
+		out.Disks = append(out.Disks, disk)
+	}
+
+	for i := 0; i < len(opts.Nics) || i < len(t.VmDefineNic); i++ {
+		var nic VmNicDefinition
+		if i < len(opts.Nics) {
+			nic = opts.Nics[i]
+		}
+		if i < len(t.VmDefineNic) {
+			fillZeroFields(reflect.ValueOf(&nic).Elem(), reflect.ValueOf(t.VmDefineNic[i]))
+		}
+		out.Nics = append(out.Nics, nic)
+	}
+
+	return out
+}
+
+// ExpandTemplate fetches a template and expands it into a machine definition (see Template.Expand).
+func (c *Client) ExpandTemplate(templateName string, opts CreateMachineOpts) (*CreateMachineOpts, error) {
+	t, err := c.GetTemplate(templateName)
+	if err != nil {
+		return nil, err
+	}
+	expanded := t.Expand(opts)
+	return &expanded, nil
+}
+
+// Helper that makes unset vm_define* sections empty lists, which the API accepts (unlike null).
+func (t *Template) setEmptySections() {
+	if t.VmDefineDisk == nil {
+		t.VmDefineDisk = []VmDiskDefinition{}
+	}
+	if t.VmDefineNic == nil {
+		t.VmDefineNic = []VmNicDefinition{}
+	}
+	if t.VmDefineSnapshot == nil {
+		t.VmDefineSnapshot = []TemplateSnapshotDefine{}
+	}
+	if t.VmDefineBackup == nil {
+		t.VmDefineBackup = []TemplateBackupDefine{}
+	}
+}
+
+// Helper that copies values of src fields to the same-named fields of dst that are not set.
+// ReqData is skipped; slices and maps are copied so that dst does not share them with src.
+func fillZeroFields(dst, src reflect.Value) {
+	srcType := src.Type()
+	for i := 0; i < srcType.NumField(); i++ {
+		field := srcType.Field(i)
+		if field.PkgPath != "" || field.Type == reflect.TypeOf(ReqData{}) {
+			continue
+		}
+		srcVal := src.Field(i)
+		dstVal := dst.FieldByName(field.Name)
+		if !dstVal.IsValid() || dstVal.Type() != field.Type || !isZeroValue(dstVal) || isZeroValue(srcVal) {
+			continue
+		}
+		switch srcVal.Kind() {
+		case reflect.Slice:
+			dstVal.Set(reflect.AppendSlice(reflect.MakeSlice(field.Type, 0, srcVal.Len()), srcVal))
+		case reflect.Map:
+			m := reflect.MakeMap(field.Type)
+			for _, k := range srcVal.MapKeys() {
+				m.SetMapIndex(k, srcVal.MapIndex(k))
+			}
+			dstVal.Set(m)
+		default:
+			dstVal.Set(srcVal)
+		}
+	}
+}
+
+func isZeroValue(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
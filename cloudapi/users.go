@@ -0,0 +1,195 @@
+package cloudapi
+
+import (
+	"net/http"
+
+	"github.com/erigones/godanube/client"
+	"github.com/erigones/godanube/errors"
+)
+
+// User represents a Danube Cloud user account.
+// Boolean attributes are pointers, so that an update can both set and clear them
+// (nil leaves the attribute unchanged); use Bool() to set them.
+// https://docs.danubecloud.org/api-reference/api/accounts_user.html
+type User struct {
+	ReqData
+	Username     string   `json:"username,omitempty"`
+	FirstName    string   `json:"first_name,omitempty"`
+	LastName     string   `json:"last_name,omitempty"`
+	Email        string   `json:"email,omitempty"`
+	IsActive     *bool    `json:"is_active,omitempty"`
+	IsSuperAdmin *bool    `json:"is_super_admin,omitempty"`
+	ApiAccess    *bool    `json:"api_access,omitempty"` // whether the user can use the API
+	Groups       []string `json:"groups,omitempty"`     // user groups (roles) the user is member of
+	DcBound      *bool    `json:"dc_bound,omitempty"`   // whether the user is dedicated to one vDC
+	Password     string   `json:"password,omitempty"`   // only for create/update, never returned
+
+	// Not settable, only for querying:
+	Created string `json:"created,omitempty"`
+}
+
+// UserApiKeys represents API keys of a user
+type UserApiKeys struct {
+	ApiKey      string `json:"api_key,omitempty"`      // key used in the es-api-key header
+	CallbackKey string `json:"callback_key,omitempty"` // key used to sign task callbacks
+}
+
+// regenerateApiKeysOpts selects which user keys are generated anew
+type regenerateApiKeysOpts struct {
+	ReqData
+	ApiKey      bool `json:"api_key,omitempty"`
+	CallbackKey bool `json:"callback_key,omitempty"`
+}
+
+// Bool returns a pointer to b, for optional boolean attributes of create and update requests.
+func Bool(b bool) *bool {
+	return &b
+}
+
+// Int returns a pointer to i, for optional numeric attributes of update requests.
+func Int(i int) *int {
+	return &i
+}
+
+// String returns a pointer to s, for optional text attributes of update requests.
+func String(s string) *string {
+	return &s
+}
+
+/*** STRUCTS FOR USER-SPECIFIC DC RESPONSES ***/
+type UserResponse struct {
+	DcResponse
+	Result User `json:"result"`
+}
+
+type UserResponseFull struct {
+	DcResponse
+	Result []User `json:"result"`
+}
+
+type UserApiKeysResponse struct {
+	DcResponse
+	Result UserApiKeys `json:"result"`
+}
+
+// ListUsers returns usernames of all users visible to the caller.
+func (c *Client) ListUsers() ([]string, error) {
+	var resp ResponseList
+	req := request{
+		method: client.GET,
+		url:    makeURL("accounts", "user"),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of users")
+	}
+	return resp.Result, nil
+}
+
+// ListUsersFull returns details of all users visible to the caller.
+func (c *Client) ListUsersFull() ([]User, error) {
+	var resp UserResponseFull
+	filter := NewFilter()
+	filter.Set("full", "true")
+	req := request{
+		method: client.GET,
+		url:    makeURL("accounts", "user"),
+		filter: filter,
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get list of users")
+	}
+	return resp.Result, nil
+}
+
+// GetUser returns details of a user.
+func (c *Client) GetUser(username string) (*User, error) {
+	var resp UserResponse
+	req := request{
+		method: client.GET,
+		url:    makeURL("accounts", "user", username),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get user \"%s\"", username)
+	}
+	return &resp.Result, nil
+}
+
+// CreateUser creates a new user account.
+func (c *Client) CreateUser(opts User) (*User, error) {
+	var resp UserResponse
+	req := request{
+		method:           client.POST,
+		url:              makeURL("accounts", "user", opts.Username),
+		reqValue:         &opts,
+		resp:             &resp,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK},
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to create user \"%s\"", opts.Username)
+	}
+	return &resp.Result, nil
+}
+
+// UpdateUser changes attributes of an existing user. Empty (nil) fields are left unchanged,
+// e.g. User{Username: "joe", IsActive: Bool(false)} deactivates the user.
+func (c *Client) UpdateUser(opts User) (*User, error) {
+	var resp UserResponse
+	req := request{
+		method:   client.PUT,
+		url:      makeURL("accounts", "user", opts.Username),
+		reqValue: &opts,
+		resp:     &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to update user \"%s\"", opts.Username)
+	}
+	return &resp.Result, nil
+}
+
+// DeleteUser deletes a user account.
+func (c *Client) DeleteUser(username string) error {
+	var resp DcResponse
+	req := request{
+		method: client.DELETE,
+		url:    makeURL("accounts", "user", username),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return errors.Newf2(err, resp.Detail, "failed to delete user \"%s\"", username)
+	}
+	return nil
+}
+
+// GetUserApiKeys returns the API and callback keys of a user.
+func (c *Client) GetUserApiKeys(username string) (*UserApiKeys, error) {
+	var resp UserApiKeysResponse
+	req := request{
+		method: client.GET,
+		url:    makeURL("accounts", "user", username, "apikeys"),
+		resp:   &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to get API keys of user \"%s\"", username)
+	}
+	return &resp.Result, nil
+}
+
+// RegenerateUserApiKeys generates new API and/or callback key for a user
+// and returns the resulting keys. The old key stops working immediately.
+func (c *Client) RegenerateUserApiKeys(username string, apiKey, callbackKey bool) (*UserApiKeys, error) {
+	var resp UserApiKeysResponse
+	opts := regenerateApiKeysOpts{ApiKey: apiKey, CallbackKey: callbackKey}
+	req := request{
+		method:   client.PUT,
+		url:      makeURL("accounts", "user", username, "apikeys"),
+		reqValue: &opts,
+		resp:     &resp,
+	}
+	if _, err := c.sendRequest(req); err != nil {
+		return nil, errors.Newf2(err, resp.Detail, "failed to regenerate API keys of user \"%s\"", username)
+	}
+	return &resp.Result, nil
+}
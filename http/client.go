@@ -19,6 +19,7 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"sync"
 
 	//"reflect"
 	//"strconv"
@@ -53,6 +54,7 @@ type Client struct {
 	logger          *log.Logger
 	trace           bool
 	lastRequestTime time.Time
+	rateLimitMu     sync.Mutex // guards lastRequestTime, the client may be shared by more goroutines
 }
 
 type ErrorResponse struct {
@@ -73,7 +75,7 @@ type RequestData struct {
 	Params     *url.Values
 	ReqValue   interface{}
 	ReqReader  io.Reader
-	ReqLength  int
+	ReqLength  int64
 }
 
 type ResponseData struct {
@@ -97,7 +99,14 @@ func New(credentials *auth.Credentials, apiVersion string, logger *log.Logger) *
 		Timeout: httpTimeout,
 	}
 	//DELME return &Client{*http.DefaultClient, MaxSendAttempts, credentials, apiVersion, logger, false}
-	return &Client{*htclient, MaxSendAttempts, credentials, apiVersion, logger, false, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+	return &Client{
+		Client:          *htclient,
+		maxSendAttempts: MaxSendAttempts,
+		credentials:     credentials,
+		apiVersion:      apiVersion,
+		logger:          logger,
+		lastRequestTime: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
 }
 
 // SetTrace allows control over whether requests will write their
@@ -302,39 +311,90 @@ func decodeStream(d *json.Decoder, into interface{}) error {
 }
 */
 
-/*DELME
-// Sends the byte array in reqData.ReqValue (if any) to the specified URL.
+// BinaryRequest streams the bytes from request.ReqReader to the specified URL.
 // Optional method arguments are passed using the RequestData object.
 // Relevant RequestData fields:
 // ReqHeaders: additional HTTP header values to add to the request.
-// ExpectedStatus: the allowed HTTP response status values, else an error is returned.
 // ReqReader: an io.Reader providing the bytes to send.
-// RespReader: assigned an io.ReadCloser instance used to read the returned data..
-func (c *Client) BinaryRequest(method, url, rfc1123Date string, request *RequestData, response *ResponseData) (err error) {
-	err = nil
-
+// ReqLength: the number of bytes to send (-1 if unknown).
+// Relevant ResponseData fields:
+// ExpectedStatus: the allowed HTTP response status values, else an error is returned.
+// RespValue: the data object to decode the JSON result into.
+// The reader is consumed only once, so the request is never retried and it is not
+// subject to the overall client timeout (uploads of big files take long).
+func (c *Client) BinaryRequest(method, url, rfc1123Date string, request *RequestData, response *ResponseData) error {
 	if request.Params != nil {
 		url += "?" + request.Params.Encode()
 	}
-	headers, err := createHeaders(request.ReqHeaders, c.credentials, contentTypeOctetStream, rfc1123Date,
-		c.apiVersion, isMantaRequest(url, c.credentials.UserAuthentication.User))
+	headers, err := createHeaders(request.ReqHeaders, c.credentials, contentTypeOctetStream, rfc1123Date, c.apiVersion)
 	if err != nil {
 		return err
 	}
-	respBody, respHeader, err := c.sendRequest(
-		method, url, request.ReqReader, request.ReqLength, headers, response.ExpectedStatus, c.logger)
+	if request.ReqHeaders.Get("Accept") == "" {
+		// the data is binary, but the API responds with JSON
+		headers.Set("Accept", contentTypeJSON)
+	}
+
+	req, err := http.NewRequest(method, url, request.ReqReader)
 	if err != nil {
-		return
+		return errors.Newf(err, "failed creating the request %s", url)
 	}
-	if response.RespReader != nil {
-		response.RespReader = respBody
+	req.Close = true
+	for header, values := range headers {
+		for _, value := range values {
+			req.Header.Add(header, value)
+		}
 	}
-	if respHeader != nil {
-		response.RespHeaders = respHeader
+	req.ContentLength = request.ReqLength
+	if c.logger != nil && c.trace {
+		c.logger.Printf("Streaming %d bytes with %s to %s\n", request.ReqLength, method, url)
+	}
+
+	c.waitForRateLimit()
+
+	streamingClient := c.Client
+	streamingClient.Timeout = 0
+	rawResp, err := streamingClient.Do(req)
+	if err != nil {
+		return errors.Newf(err, "failed executing the request %s", url)
 	}
-	return
+	defer rawResp.Body.Close()
+
+	if rawResp.StatusCode == http.StatusTooManyRequests {
+		return errors.NewRequestThrottledf(nil, "", "Request throttled %s", url)
+	}
+	expectedStatus := response.ExpectedStatus
+	if len(expectedStatus) == 0 {
+		expectedStatus = []int{http.StatusOK}
+	}
+	foundStatus := false
+	for _, status := range expectedStatus {
+		if rawResp.StatusCode == status {
+			foundStatus = true
+			break
+		}
+	}
+	var reqErr error
+	if !foundStatus {
+		reqErr = handleError(url, rawResp)
+	}
+
+	respData, err := ioutil.ReadAll(rawResp.Body)
+	if err != nil {
+		return errors.Newf(err, "failed reading the response body")
+	}
+	if c.logger != nil && c.trace {
+		c.logger.Printf("Response data: %s", respData)
+	}
+	if len(respData) > 0 && response.RespValue != nil {
+		if err = json.Unmarshal(respData, response.RespValue); err != nil && reqErr == nil {
+			return errors.Newf(err, "failed unmarshaling the response body: %s", respData)
+		}
+	}
+	response.RespHeaders = &rawResp.Header
+
+	return reqErr
 }
-*/
 
 // Sends the specified request to URL and checks that the HTTP response status is as expected.
 // reqReader: a reader returning the data to send.
@@ -408,16 +468,7 @@ func (c *Client) sendRateLimitedRequest(method, URL string, headers http.Header,
 		}
 		req.ContentLength = int64(len(reqData))
 
-		if c.lastRequestTime.Add(minTimeBetweenReqs).After(time.Now()) {
-			sleepTime := minTimeBetweenReqs - (time.Now().Sub(c.lastRequestTime))
-			/*
-				if logger != nil && c.trace {
-					logger.Printf("Sleeping %.2f seconds between requests", sleepTime.Seconds())
-				}
-			*/
-			time.Sleep(sleepTime)
-		}
-		c.lastRequestTime = time.Now()
+		c.waitForRateLimit()
 
 		resp, err = c.Do(req)
 		if err != nil {
@@ -459,6 +510,18 @@ func (c *Client) sendRateLimitedRequest(method, URL string, headers http.Header,
 	return nil, errors.Newf(err, "Maximum number of attempts (%d) reached sending request to %s", c.maxSendAttempts, URL)
 }
 
+// waitForRateLimit blocks until the next request can be sent without exceeding
+// the server request rate. Requests from all goroutines sharing the client are serialized here.
+func (c *Client) waitForRateLimit() {
+	c.rateLimitMu.Lock()
+	defer c.rateLimitMu.Unlock()
+	if c.lastRequestTime.Add(minTimeBetweenReqs).After(time.Now()) {
+		sleepTime := minTimeBetweenReqs - (time.Now().Sub(c.lastRequestTime))
+		time.Sleep(sleepTime)
+	}
+	c.lastRequestTime = time.Now()
+}
+
 type HttpError struct {
 	StatusCode      int
 	Data            map[string][]string
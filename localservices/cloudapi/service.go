@@ -36,7 +36,7 @@ type CloudAPI struct {
 	snapshots     map[string][]cloudapi.Snapshot
 	firewallRules []*cloudapi.FirewallRule
 	networks      []cloudapi.Network
-	fabricVLANs   map[int16]*fabricVLAN
+	overlayRules  map[string]map[string]*cloudapi.OverlayRule // node -> rule name -> rule
 }
 
 type machine struct {
@@ -45,12 +45,6 @@ type machine struct {
 	NetworkNICs map[string]string        `json:"-"`
 }
 
-// fabricNetwork is a container for a fabric network and it's associated VLANs
-type fabricVLAN struct {
-	cloudapi.FabricVLAN
-	Networks map[string]*cloudapi.FabricNetwork `json:"-"`
-}
-
 // New makes a new *CloudAPI service with the given information
 func New(serviceURL, userAccount string) *CloudAPI {
 	URL, err := url.Parse(serviceURL)
@@ -67,7 +61,7 @@ func New(serviceURL, userAccount string) *CloudAPI {
 		machines      []*machine
 		snapshots     = map[string][]cloudapi.Snapshot{}
 		firewallRules []*cloudapi.FirewallRule
-		fabricVLANs   = map[int16]*fabricVLAN{}
+		overlayRules  = map[string]map[string]*cloudapi.OverlayRule{}
 	)
 
 	cloudapiService := &CloudAPI{
@@ -77,7 +71,7 @@ func New(serviceURL, userAccount string) *CloudAPI {
 		machines:      machines,
 		snapshots:     snapshots,
 		firewallRules: firewallRules,
-		fabricVLANs:   fabricVLANs,
+		overlayRules:  overlayRules,
 		networks: []cloudapi.Network{
 			{Id: "123abc4d-0011-aabb-2233-ccdd4455", Name: "Test-Joyent-Public", Public: true},
 			{Id: "456def0a-33ff-7f8e-9a0b-33bb44cc", Name: "Test-Joyent-Private", Public: false},
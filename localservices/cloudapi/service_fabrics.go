@@ -2,150 +2,163 @@ package cloudapi
 
 import (
 	"fmt"
-	"math/rand"
 
 	"github.com/erigones/godanube/cloudapi"
 	"github.com/erigones/godanube/localservices"
 )
 
-func (c *CloudAPI) getFabricWrapper(vlanID int16) (*fabricVLAN, error) {
-	vlan, present := c.fabricVLANs[vlanID]
-	if !present {
-		return nil, fmt.Errorf("VLAN %d not found", vlanID)
-	}
+// Overlays API
 
-	return vlan, nil
-}
+// ListOverlayRules lists overlay rules defined on a compute node
+func (c *CloudAPI) ListOverlayRules(node string) ([]cloudapi.OverlayRule, error) {
+	if err := c.ProcessFunctionHook(c, node); err != nil {
+		return nil, err
+	}
 
-// ListFabricVLANs lists VLANs
-func (c *CloudAPI) ListFabricVLANs() ([]cloudapi.FabricVLAN, error) {
-	out := []cloudapi.FabricVLAN{}
-	for _, vlan := range c.fabricVLANs {
-		out = append(out, vlan.FabricVLAN)
+	out := []cloudapi.OverlayRule{}
+	for _, rule := range c.overlayRules[node] {
+		out = append(out, *rule)
 	}
 
 	return out, nil
 }
 
-// GetFabricVLAN retrieves a single VLAN by ID
-func (c *CloudAPI) GetFabricVLAN(vlanID int16) (*cloudapi.FabricVLAN, error) {
-	vlan, err := c.getFabricWrapper(vlanID)
-	if err != nil {
+// GetOverlayRule retrieves a single overlay rule of a compute node
+func (c *CloudAPI) GetOverlayRule(node, name string) (*cloudapi.OverlayRule, error) {
+	if err := c.ProcessFunctionHook(c, node, name); err != nil {
 		return nil, err
 	}
 
-	return &vlan.FabricVLAN, nil
+	rule, present := c.overlayRules[node][name]
+	if !present {
+		return nil, fmt.Errorf("Overlay rule %s not found on node %s", name, node)
+	}
+
+	return rule, nil
 }
 
-// CreateFabricVLAN creates a new VLAN with the specified options
-func (c *CloudAPI) CreateFabricVLAN(vlan cloudapi.FabricVLAN) (*cloudapi.FabricVLAN, error) {
-	id := int16(rand.Intn(4095 + 1))
-	vlan.Id = id
+// CreateOverlayRule creates a new overlay rule on the compute node rule.Node
+func (c *CloudAPI) CreateOverlayRule(rule cloudapi.OverlayRule) (*cloudapi.OverlayRule, error) {
+	if err := c.ProcessFunctionHook(c, rule); err != nil {
+		return nil, err
+	}
 
-	c.fabricVLANs[id] = &fabricVLAN{
-		FabricVLAN: vlan,
-		Networks:   make(map[string]*cloudapi.FabricNetwork),
+	if _, present := c.overlayRules[rule.Node][rule.Name]; present {
+		return nil, fmt.Errorf("Overlay rule %s already exists on node %s", rule.Name, rule.Node)
+	}
+	if rule.Port == 0 {
+		rule.Port = cloudapi.DefaultOverlayPort
 	}
+	if rule.ArpFailurePolicy == "" {
+		rule.ArpFailurePolicy = cloudapi.OverlayArpFailureDrop
+	}
+	if c.overlayRules[rule.Node] == nil {
+		c.overlayRules[rule.Node] = map[string]*cloudapi.OverlayRule{}
+	}
+	c.overlayRules[rule.Node][rule.Name] = &rule
 
-	return &vlan, nil
+	return &rule, nil
 }
 
-// UpdateFabricVLAN updates a given VLAN with new fields
-func (c *CloudAPI) UpdateFabricVLAN(new cloudapi.FabricVLAN) (*cloudapi.FabricVLAN, error) {
-	current, err := c.GetFabricVLAN(new.Id)
+// UpdateOverlayRule updates an existing overlay rule with new fields
+func (c *CloudAPI) UpdateOverlayRule(new cloudapi.OverlayRule) (*cloudapi.OverlayRule, error) {
+	current, err := c.GetOverlayRule(new.Node, new.Name)
 	if err != nil {
 		return nil, err
 	}
 
-	current.Name = new.Name
-	current.Description = new.Description
+	if new.Port != 0 {
+		current.Port = new.Port
+	}
+	if new.Ip != "" {
+		current.Ip = new.Ip
+	}
+	if new.ArpFailurePolicy != "" {
+		current.ArpFailurePolicy = new.ArpFailurePolicy
+	}
 
 	return current, nil
 }
 
-// DeleteFabricVLAN delets a given VLAN as specified by ID
-func (c *CloudAPI) DeleteFabricVLAN(vlanID int16) error {
-	_, present := c.fabricVLANs[vlanID]
-	if !present {
-		return fmt.Errorf("VLAN %d not found", vlanID)
+// DeleteOverlayRule deletes an overlay rule from a compute node.
+// The last rule of a name cannot be deleted while networks use it.
+func (c *CloudAPI) DeleteOverlayRule(node, name string) error {
+	if err := c.ProcessFunctionHook(c, node, name); err != nil {
+		return err
 	}
 
-	delete(c.fabricVLANs, vlanID)
-	return nil
-}
-
-// ListFabricNetworks lists the networks inside the given VLAN
-func (c *CloudAPI) ListFabricNetworks(vlanID int16) ([]cloudapi.FabricNetwork, error) {
-	vlan, err := c.getFabricWrapper(vlanID)
-	if err != nil {
-		return nil, err
+	if _, present := c.overlayRules[node][name]; !present {
+		return fmt.Errorf("Overlay rule %s not found on node %s", name, node)
 	}
-
-	out := []cloudapi.FabricNetwork{}
-	for _, network := range vlan.Networks {
-		out = append(out, *network)
+	if c.overlayRuleNodes(name) == 1 {
+		for _, n := range c.networks {
+			if n.Nic_tag_type == cloudapi.NicTagTypeOverlay && n.Nic_tag == name {
+				return fmt.Errorf("Overlay rule %s is used by network %s", name, n.Name)
+			}
+		}
 	}
 
-	return out, nil
+	delete(c.overlayRules[node], name)
+	return nil
 }
 
-// GetFabricNetwork gets a single network by VLAN and Network IDs
-func (c *CloudAPI) GetFabricNetwork(vlanID int16, networkID string) (*cloudapi.FabricNetwork, error) {
-	vlan, err := c.getFabricWrapper(vlanID)
-	if err != nil {
+// CreateOverlayNetwork creates a network on top of an existing overlay rule
+func (c *CloudAPI) CreateOverlayNetwork(network cloudapi.Network) (*cloudapi.Network, error) {
+	if err := c.ProcessFunctionHook(c, network); err != nil {
 		return nil, err
 	}
 
-	network, present := vlan.Networks[networkID]
-	if !present {
-		return nil, fmt.Errorf("Network %s not found", networkID)
+	if network.Nic_tag_type != cloudapi.NicTagTypeOverlay {
+		return nil, fmt.Errorf("Network %s is not an overlay network", network.Name)
+	}
+	if c.overlayRuleNodes(network.Nic_tag) == 0 {
+		return nil, fmt.Errorf("Overlay rule %s not found", network.Nic_tag)
+	}
+	if network.Vxlan_id < 1 || network.Vxlan_id > cloudapi.MaxVxlanId {
+		return nil, fmt.Errorf("Invalid VXLAN ID %d", network.Vxlan_id)
+	}
+	for _, n := range c.networks {
+		if n.Name == network.Name {
+			return nil, fmt.Errorf("Network %s already exists", network.Name)
+		}
+		if n.Nic_tag == network.Nic_tag && n.Vxlan_id == network.Vxlan_id {
+			return nil, fmt.Errorf("VXLAN ID %d is already used by network %s", network.Vxlan_id, n.Name)
+		}
 	}
 
-	return network, nil
-}
-
-// CreateFabricNetwork creates a new fabric network
-func (c *CloudAPI) CreateFabricNetwork(vlanID int16, opts cloudapi.CreateFabricNetworkOpts) (*cloudapi.FabricNetwork, error) {
 	id, err := localservices.NewUUID()
 	if err != nil {
 		return nil, err
 	}
+	network.Uuid = id
+	c.networks = append(c.networks, network)
 
-	vlan, err := c.getFabricWrapper(vlanID)
-	if err != nil {
+	return &network, nil
+}
+
+// ListOverlayNetworks lists networks using the overlay rule name (all overlay networks if empty)
+func (c *CloudAPI) ListOverlayNetworks(name string) ([]cloudapi.Network, error) {
+	if err := c.ProcessFunctionHook(c, name); err != nil {
 		return nil, err
 	}
 
-	vlan.Networks[id] = &cloudapi.FabricNetwork{
-		Id:               id,
-		Name:             opts.Name,
-		Public:           false,
-		Fabric:           true,
-		Description:      opts.Description,
-		Subnet:           opts.Subnet,
-		ProvisionStartIp: opts.ProvisionStartIp,
-		ProvisionEndIp:   opts.ProvisionEndIp,
-		Gateway:          opts.Gateway,
-		Routes:           opts.Routes,
-		InternetNAT:      opts.InternetNAT,
-		VLANId:           vlanID,
+	out := []cloudapi.Network{}
+	for _, n := range c.networks {
+		if n.Nic_tag_type == cloudapi.NicTagTypeOverlay && (name == "" || n.Nic_tag == name) {
+			out = append(out, n)
+		}
 	}
 
-	return vlan.Networks[id], nil
+	return out, nil
 }
 
-// DeleteFabricNetwork deletes an existing fabric network
-func (c *CloudAPI) DeleteFabricNetwork(vlanID int16, networkID string) error {
-	vlan, err := c.getFabricWrapper(vlanID)
-	if err != nil {
-		return err
+// overlayRuleNodes returns the number of nodes where the overlay rule name is defined
+func (c *CloudAPI) overlayRuleNodes(name string) int {
+	count := 0
+	for _, rules := range c.overlayRules {
+		if _, present := rules[name]; present {
+			count++
+		}
 	}
-
-	_, present := vlan.Networks[networkID]
-	if !present {
-		return fmt.Errorf("Network %s not found", networkID)
-	}
-
-	delete(vlan.Networks, networkID)
-	return nil
+	return count
 }
@@ -12,6 +12,7 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -161,7 +162,7 @@ func (c *CloudAPI) handleListKeys(w http.ResponseWriter, r *http.Request, params
 	if keys == nil {
 		keys = []cloudapi.Key{}
 	}
-	return sendJSON(http.StatusOK, keys, w, r)
+	return sendJSON(http.StatusOK, cloudapi.KeyResponseFull{DcResponse: cloudapi.DcResponse{Status: "SUCCESS"}, Result: keys}, w, r)
 }
 
 func (c *CloudAPI) handleGetKey(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
@@ -172,7 +173,7 @@ func (c *CloudAPI) handleGetKey(w http.ResponseWriter, r *http.Request, params h
 	if key == nil {
 		key = &cloudapi.Key{}
 	}
-	return sendJSON(http.StatusOK, key, w, r)
+	return sendJSON(http.StatusOK, cloudapi.KeyResponse{DcResponse: cloudapi.DcResponse{Status: "SUCCESS"}, Result: *key}, w, r)
 }
 
 func (c *CloudAPI) handleCreateKey(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
@@ -189,14 +190,14 @@ func (c *CloudAPI) handleCreateKey(w http.ResponseWriter, r *http.Request, param
 		}
 	}
 
-	key, err := c.CreateKey(opts.Name, opts.Key)
+	key, err := c.CreateKey(params.ByName("id"), opts.Key)
 	if err != nil {
 		return err
 	}
 	if key == nil {
 		key = &cloudapi.Key{}
 	}
-	return sendJSON(http.StatusCreated, key, w, r)
+	return sendJSON(http.StatusCreated, cloudapi.KeyResponse{DcResponse: cloudapi.DcResponse{Status: "SUCCESS"}, Result: *key}, w, r)
 }
 
 func (c *CloudAPI) handleDeleteKey(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
@@ -205,7 +206,7 @@ func (c *CloudAPI) handleDeleteKey(w http.ResponseWriter, r *http.Request, param
 		return err // TODO: handle 404
 	}
 
-	return sendJSON(http.StatusNoContent, nil, w, r)
+	return sendJSON(http.StatusOK, cloudapi.DcResponse{Status: "SUCCESS"}, w, r)
 }
 
 // images
@@ -379,9 +380,6 @@ func (c *CloudAPI) handleUpdateMachine(w http.ResponseWriter, r *http.Request, p
 	case "reboot":
 		err = c.RebootMachine(id)
 
-	case "resize":
-		err = c.ResizeMachine(id, r.URL.Query().Get("package"))
-
 	case "rename":
 		err = c.RenameMachine(id, r.URL.Query().Get("name"))
 
@@ -401,6 +399,60 @@ func (c *CloudAPI) handleUpdateMachine(w http.ResponseWriter, r *http.Request, p
 	return sendJSON(http.StatusAccepted, nil, w, r)
 }
 
+func (c *CloudAPI) handleGetVm(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	m, err := c.getMachineWrapper(params.ByName("id"))
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(http.StatusOK, cloudapi.VmResponse{DcResponse: cloudapi.DcResponse{Status: "SUCCESS"}, Result: c.vmDetails(m)}, w, r)
+}
+
+func (c *CloudAPI) handleMachineStatus(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	var opts struct {
+		Freeze   bool `json:"freeze"`
+		Unfreeze bool `json:"unfreeze"`
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if len(body) > 0 {
+		if err = json.Unmarshal(body, &opts); err != nil {
+			return err
+		}
+	}
+
+	id := params.ByName("id")
+	switch params.ByName("action") {
+	case "stop":
+		if opts.Unfreeze {
+			err = c.UnfreezeMachine(id)
+		} else if err = c.StopMachine(id); err == nil && opts.Freeze {
+			err = c.FreezeMachine(id)
+		}
+
+	case "start":
+		if opts.Unfreeze {
+			err = c.UnfreezeMachine(id)
+		}
+		if err == nil {
+			err = c.StartMachine(id)
+		}
+
+	case "reboot":
+		err = c.RebootMachine(id)
+
+	default:
+		return ErrNotAllowed
+	}
+
+	if err != nil {
+		return err
+	}
+	return sendJSON(http.StatusOK, cloudapi.DcResponse{Status: "SUCCESS"}, w, r)
+}
+
 func (c *CloudAPI) handleDeleteMachine(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
 	err := c.DeleteMachine(params.ByName("id"))
 	if err != nil {
@@ -420,141 +472,166 @@ func (c *CloudAPI) handleMachineFirewallRules(w http.ResponseWriter, r *http.Req
 	return sendJSON(http.StatusOK, rules, w, r)
 }
 
-// machine metadata
+// machine definition (Danube)
 
-func (c *CloudAPI) handleGetMachineMetadata(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	metadata, err := c.GetMachineMetadata(params.ByName("id"))
-	if err != nil {
-		return err
+// vmDetails returns the Danube list view of a machine (all machines are owned by the account)
+func (c *CloudAPI) vmDetails(m *machine) cloudapi.VmDetails {
+	return cloudapi.VmDetails{
+		Hostname: m.Name,
+		Uuid:     m.Id,
+		Owner:    c.ServiceInstance.UserAccount,
+		Status:   m.State,
+		Ram:      m.Memory,
+		Disk:     m.Disk,
+		Ips:      m.IPs,
+		Tags:     tagList(m.Tags),
 	}
-
-	return sendJSON(http.StatusOK, metadata, w, r)
 }
 
-func (c *CloudAPI) handleUpdateMachineMetadata(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	metadata := make(map[string]string)
-
-	body, err := ioutil.ReadAll(r.Body)
-	defer r.Body.Close()
-	if err != nil {
-		return err
+func (c *CloudAPI) handleListVms(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	query := r.URL.Query()
+	owner, status := query.Get("owner"), query.Get("status")
+	vms := []cloudapi.VmDetails{}
+	for _, m := range c.machines {
+		vm := c.vmDetails(m)
+		if hasTags(m, query["tag"]) && (owner == "" || vm.Owner == owner) && (status == "" || vm.Status == status) {
+			vms = append(vms, vm)
+		}
 	}
-
-	err = json.Unmarshal(body, &metadata)
-	if err != nil {
+	if err := sortVms(vms, query.Get("order_by")); err != nil {
 		return err
 	}
-
-	metadata, err = c.UpdateMachineMetadata(params.ByName("id"), metadata)
-	if err != nil {
-		return err
+	if page, _ := strconv.Atoi(query.Get("page")); page > 0 {
+		perPage, _ := strconv.Atoi(query.Get("per_page"))
+		if perPage <= 0 {
+			return ErrBadRequest
+		}
+		vms = pageVms(vms, page, perPage)
 	}
 
-	return sendJSON(http.StatusOK, metadata, w, r)
+	return sendJSON(http.StatusOK, cloudapi.VmsResponse{DcResponse: cloudapi.DcResponse{Status: "SUCCESS"}, Result: vms}, w, r)
 }
 
-func (c *CloudAPI) handleDeleteMachineMetadata(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	err := c.DeleteMachineMetadata(params.ByName("id"), params.ByName("key"))
-	if err != nil {
-		return err
+// sortVms orders machines by the comma separated fields of order_by ("-" prefix for descending).
+func sortVms(vms []cloudapi.VmDetails, orderBy string) error {
+	if orderBy == "" {
+		return nil
 	}
-
-	return sendJSON(http.StatusNoContent, nil, w, r)
-}
-
-func (c *CloudAPI) handleDeleteAllMachineMetadata(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	err := c.DeleteAllMachineMetadata(params.ByName("id"))
-	if err != nil {
-		return err
+	fields := strings.Split(orderBy, ",")
+	for _, field := range fields {
+		switch strings.TrimPrefix(field, "-") {
+		case "hostname", "uuid", "alias":
+		default:
+			return ErrBadRequest
+		}
 	}
-
-	return sendJSON(http.StatusNoContent, nil, w, r)
+	value := func(vm *cloudapi.VmDetails, field string) string {
+		switch field {
+		case "uuid":
+			return vm.Uuid
+		case "alias":
+			return vm.Alias
+		}
+		return vm.Hostname
+	}
+	sort.SliceStable(vms, func(i, j int) bool {
+		for _, field := range fields {
+			name := strings.TrimPrefix(field, "-")
+			a, b := value(&vms[i], name), value(&vms[j], name)
+			if a == b {
+				continue
+			}
+			return (a < b) != strings.HasPrefix(field, "-")
+		}
+		return false
+	})
+	return nil
 }
 
-// machine tags
-
-func (c *CloudAPI) handleListMachineTags(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	tags, err := c.ListMachineTags(params.ByName("id"))
-	if err != nil {
-		return err
+// pageVms returns the page number page (counted from 1) of perPage machines.
+func pageVms(vms []cloudapi.VmDetails, page, perPage int) []cloudapi.VmDetails {
+	start := (page - 1) * perPage
+	if start >= len(vms) {
+		return []cloudapi.VmDetails{}
 	}
-
-	return sendJSON(http.StatusOK, tags, w, r)
+	end := start + perPage
+	if end > len(vms) {
+		end = len(vms)
+	}
+	return vms[start:end]
 }
 
-func (c *CloudAPI) handleAddMachineTags(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	tags := make(map[string]string)
-
-	body, err := ioutil.ReadAll(r.Body)
-	defer r.Body.Close()
+func (c *CloudAPI) machineDefinition(machineID string) (*cloudapi.MachineDefinition, error) {
+	m, err := c.getMachineWrapper(machineID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	err = json.Unmarshal(body, &tags)
+	mdata, err := c.GetMachineMdata(machineID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	tags, err = c.AddMachineTags(params.ByName("id"), tags)
+	return &cloudapi.MachineDefinition{
+		Name:  m.Name,
+		Uuid:  m.Id,
+		Ram:   m.Memory,
+		Tags:  tagList(m.Tags),
+		Mdata: mdata,
+	}, nil
+}
+
+func (c *CloudAPI) handleGetMachineDefinition(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	definition, err := c.machineDefinition(params.ByName("id"))
 	if err != nil {
 		return err
 	}
 
-	return sendJSON(http.StatusOK, tags, w, r)
+	return sendJSON(http.StatusOK, cloudapi.CreateMachineResponse{DcResponse: cloudapi.DcResponse{Status: "SUCCESS"}, Result: *definition}, w, r)
 }
 
-func (c *CloudAPI) handleReplaceMachineTags(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	tags := make(map[string]string)
+func (c *CloudAPI) handleUpdateMachineDefinition(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	var changes struct {
+		Tags  *[]string          `json:"tags"`
+		Mdata *map[string]string `json:"mdata"`
+	}
 
 	body, err := ioutil.ReadAll(r.Body)
 	defer r.Body.Close()
 	if err != nil {
 		return err
 	}
-
-	err = json.Unmarshal(body, &tags)
-	if err != nil {
+	if err = json.Unmarshal(body, &changes); err != nil {
 		return err
 	}
 
-	tags, err = c.ReplaceMachineTags(params.ByName("id"), tags)
-	if err != nil {
-		return err
+	id := params.ByName("id")
+	if changes.Tags != nil {
+		if _, err = c.ReplaceMachineTags(id, *changes.Tags); err != nil {
+			return err
+		}
 	}
-
-	return sendJSON(http.StatusOK, tags, w, r)
-}
-
-func (c *CloudAPI) handleDeleteMachineTags(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	err := c.DeleteMachineTags(params.ByName("id"))
-	if err != nil {
-		return err
+	if changes.Mdata != nil {
+		if _, err = c.ReplaceMachineMdata(id, *changes.Mdata); err != nil {
+			return err
+		}
 	}
 
-	return sendJSON(http.StatusNoContent, nil, w, r)
-}
-
-func (c *CloudAPI) handleDeleteMachineTag(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	err := c.DeleteMachineTag(params.ByName("id"), params.ByName("tag"))
+	definition, err := c.machineDefinition(id)
 	if err != nil {
 		return err
 	}
+	// tags are kept only in the Danube database, mdata have to be applied to the VM
+	definition.Changed = changes.Mdata != nil
 
-	return sendJSON(http.StatusNoContent, nil, w, r)
+	return sendJSON(http.StatusOK, cloudapi.CreateMachineResponse{DcResponse: cloudapi.DcResponse{Status: "SUCCESS"}, Result: *definition}, w, r)
 }
 
-func (c *CloudAPI) handleGetMachineTag(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	tag, err := c.GetMachineTag(params.ByName("id"), params.ByName("tag"))
-	if err != nil {
+func (c *CloudAPI) handleApplyMachineDefinition(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	if _, err := c.getMachineWrapper(params.ByName("id")); err != nil {
 		return err
 	}
 
-	w.Header().Add("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(tag))
-
-	return nil
+	return sendJSON(http.StatusOK, cloudapi.DcResponse{Status: "SUCCESS"}, w, r)
 }
 
 // NICs
@@ -735,109 +812,90 @@ func (c *CloudAPI) handleGetNetwork(w http.ResponseWriter, r *http.Request, para
 	return sendJSON(http.StatusOK, network, w, r)
 }
 
-// Fabrics + VLANs and Networks
+// Overlay rules and overlay networks
 
-func (c *CloudAPI) handleListFabricVLANs(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	vlans, err := c.ListFabricVLANs()
+func (c *CloudAPI) handleListOverlayRules(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	rules, err := c.ListOverlayRules(params.ByName("node"))
 	if err != nil {
 		return err
 	}
 
-	return sendJSON(http.StatusOK, vlans, w, r)
+	return sendJSON(http.StatusOK, cloudapi.OverlayRuleResponseFull{DcResponse: cloudapi.DcResponse{Status: "SUCCESS"}, Result: rules}, w, r)
 }
 
-func (c *CloudAPI) handleCreateFabricVLAN(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	body, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		return err
-	}
-	if len(body) == 0 {
-		return ErrBadRequest
-	}
-
-	var opts cloudapi.FabricVLAN
-	if err = json.Unmarshal(body, &opts); err != nil {
-		return err
-	}
-
-	vlan, err := c.CreateFabricVLAN(opts)
+func (c *CloudAPI) handleGetOverlayRule(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	rule, err := c.GetOverlayRule(params.ByName("node"), params.ByName("name"))
 	if err != nil {
 		return err
 	}
 
-	return sendJSON(http.StatusCreated, vlan, w, r)
+	return sendJSON(http.StatusOK, cloudapi.OverlayRuleResponse{DcResponse: cloudapi.DcResponse{Status: "SUCCESS"}, Result: *rule}, w, r)
 }
 
-func (c *CloudAPI) handleGetFabricVLAN(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	id, err := strconv.Atoi(params.ByName("id"))
+func (c *CloudAPI) readOverlayRule(r *http.Request, params httprouter.Params) (cloudapi.OverlayRule, error) {
+	var opts cloudapi.OverlayRule
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		return err
+		return opts, err
 	}
-
-	vlan, err := c.GetFabricVLAN(int16(id))
-	if err != nil {
-		return err
+	if len(body) > 0 {
+		if err = json.Unmarshal(body, &opts); err != nil {
+			return opts, err
+		}
 	}
+	opts.Node = params.ByName("node")
+	opts.Name = params.ByName("name")
 
-	return sendJSON(http.StatusOK, vlan, w, r)
+	return opts, nil
 }
 
-func (c *CloudAPI) handleUpdateFabricVLAN(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	body, err := ioutil.ReadAll(r.Body)
+func (c *CloudAPI) handleCreateOverlayRule(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	opts, err := c.readOverlayRule(r, params)
 	if err != nil {
 		return err
 	}
-	if len(body) == 0 {
-		return ErrBadRequest
-	}
 
-	var opts cloudapi.FabricVLAN
-	if err = json.Unmarshal(body, &opts); err != nil {
-		return err
-	}
-
-	vlan, err := c.UpdateFabricVLAN(opts)
+	rule, err := c.CreateOverlayRule(opts)
 	if err != nil {
 		return err
 	}
 
-	return sendJSON(http.StatusAccepted, vlan, w, r)
+	return sendJSON(http.StatusCreated, cloudapi.OverlayRuleResponse{DcResponse: cloudapi.DcResponse{Status: "SUCCESS"}, Result: *rule}, w, r)
 }
 
-func (c *CloudAPI) handleDeleteFabricVLAN(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	id, err := strconv.Atoi(params.ByName("id"))
+func (c *CloudAPI) handleUpdateOverlayRule(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	opts, err := c.readOverlayRule(r, params)
 	if err != nil {
 		return err
 	}
 
-	err = c.DeleteFabricVLAN(int16(id))
+	rule, err := c.UpdateOverlayRule(opts)
 	if err != nil {
 		return err
 	}
 
-	return sendJSON(http.StatusNoContent, nil, w, r)
+	return sendJSON(http.StatusOK, cloudapi.OverlayRuleResponse{DcResponse: cloudapi.DcResponse{Status: "SUCCESS"}, Result: *rule}, w, r)
 }
 
-func (c *CloudAPI) handleListFabricNetworks(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	id, err := strconv.Atoi(params.ByName("id"))
+func (c *CloudAPI) handleDeleteOverlayRule(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	err := c.DeleteOverlayRule(params.ByName("node"), params.ByName("name"))
 	if err != nil {
 		return err
 	}
 
-	networks, err := c.ListFabricNetworks(int16(id))
-	if err != nil {
-		return err
-	}
-
-	return sendJSON(http.StatusOK, networks, w, r)
+	return sendJSON(http.StatusOK, cloudapi.DcResponse{Status: "SUCCESS"}, w, r)
 }
 
-func (c *CloudAPI) handleCreateFabricNetwork(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	id, err := strconv.Atoi(params.ByName("id"))
+func (c *CloudAPI) handleListOverlayNetworks(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
+	networks, err := c.ListOverlayNetworks("")
 	if err != nil {
 		return err
 	}
 
+	return sendJSON(http.StatusOK, cloudapi.NetworkResponseFull{DcResponse: cloudapi.DcResponse{Status: "SUCCESS"}, Result: networks}, w, r)
+}
+
+func (c *CloudAPI) handleCreateOverlayNetwork(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		return err
@@ -846,45 +904,18 @@ func (c *CloudAPI) handleCreateFabricNetwork(w http.ResponseWriter, r *http.Requ
 		return ErrBadRequest
 	}
 
-	var opts cloudapi.CreateFabricNetworkOpts
+	var opts cloudapi.Network
 	if err = json.Unmarshal(body, &opts); err != nil {
 		return err
 	}
+	opts.Name = params.ByName("name")
 
-	network, err := c.CreateFabricNetwork(int16(id), opts)
+	network, err := c.CreateOverlayNetwork(opts)
 	if err != nil {
 		return err
 	}
 
-	return sendJSON(http.StatusCreated, network, w, r)
-}
-
-func (c *CloudAPI) handleGetFabricNetwork(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	id, err := strconv.Atoi(params.ByName("id"))
-	if err != nil {
-		return err
-	}
-
-	network, err := c.GetFabricNetwork(int16(id), params.ByName("network"))
-	if err != nil {
-		return err
-	}
-
-	return sendJSON(http.StatusOK, network, w, r)
-}
-
-func (c *CloudAPI) handleDeleteFabricNetwork(w http.ResponseWriter, r *http.Request, params httprouter.Params) error {
-	id, err := strconv.Atoi(params.ByName("id"))
-	if err != nil {
-		return err
-	}
-
-	err = c.DeleteFabricNetwork(int16(id), params.ByName("network"))
-	if err != nil {
-		return err
-	}
-
-	return sendJSON(http.StatusNoContent, nil, w, r)
+	return sendJSON(http.StatusCreated, cloudapi.NetworkResponse{DcResponse: cloudapi.DcResponse{Status: "SUCCESS"}, Result: *network}, w, r)
 }
 
 // ListServices handler
@@ -919,14 +950,14 @@ func (c *CloudAPI) SetupHTTP(mux *httprouter.Router) {
 	mux.NotFound = NotFound{}
 	mux.MethodNotAllowed = MethodNotAllowed{}
 
-	// keys
-	keysRoute := baseRoute + "/keys"
+	// keys (the double keeps one set of keys for all users)
+	keysRoute := baseRoute + "/accounts/user/:user/sshkey"
 	mux.GET(keysRoute, c.handler((*CloudAPI).handleListKeys))
-	mux.POST(keysRoute, c.handler((*CloudAPI).handleCreateKey))
 
 	// key
 	keyRoute := keysRoute + "/:id"
 	mux.GET(keyRoute, c.handler((*CloudAPI).handleGetKey))
+	mux.POST(keyRoute, c.handler((*CloudAPI).handleCreateKey))
 	mux.DELETE(keyRoute, c.handler((*CloudAPI).handleDeleteKey))
 
 	// images
@@ -959,27 +990,16 @@ func (c *CloudAPI) SetupHTTP(mux *httprouter.Router) {
 	mux.POST(machineRoute, c.handler((*CloudAPI).handleUpdateMachine))
 	mux.DELETE(machineRoute, c.handler((*CloudAPI).handleDeleteMachine))
 
-	// machine metadata
-	machineMetadataRoute := machineRoute + "/metadata"
-	mux.GET(machineMetadataRoute, c.handler((*CloudAPI).handleGetMachineMetadata))
-	mux.POST(machineMetadataRoute, c.handler((*CloudAPI).handleUpdateMachineMetadata))
-	mux.DELETE(machineMetadataRoute, c.handler((*CloudAPI).handleDeleteAllMachineMetadata))
-
-	// machine metadata (individual key)
-	machineMetadataKeyRoute := machineMetadataRoute + "/:key"
-	mux.DELETE(machineMetadataKeyRoute, c.handler((*CloudAPI).handleDeleteMachineMetadata))
-
-	// machine tags
-	machineTagsRoute := machineRoute + "/tags"
-	mux.GET(machineTagsRoute, c.handler((*CloudAPI).handleListMachineTags))
-	mux.POST(machineTagsRoute, c.handler((*CloudAPI).handleAddMachineTags))
-	mux.PUT(machineTagsRoute, c.handler((*CloudAPI).handleReplaceMachineTags))
-	mux.DELETE(machineTagsRoute, c.handler((*CloudAPI).handleDeleteMachineTags))
+	// machine status actions
+	mux.GET(baseRoute+"/vm/:id/status", c.handler((*CloudAPI).handleGetVm))
+	mux.PUT(baseRoute+"/vm/:id/status/:action", c.handler((*CloudAPI).handleMachineStatus))
 
-	// machine tag
-	machineTagRoute := machineTagsRoute + "/:tag"
-	mux.GET(machineTagRoute, c.handler((*CloudAPI).handleGetMachineTag))
-	mux.DELETE(machineTagRoute, c.handler((*CloudAPI).handleDeleteMachineTag))
+	// machine definition and vm list (Danube)
+	mux.GET(baseRoute+"/vm", c.handler((*CloudAPI).handleListVms))
+	mux.GET(baseRoute+"/vm/:id", c.handler((*CloudAPI).handleGetVm))
+	mux.PUT(baseRoute+"/vm/:id", c.handler((*CloudAPI).handleApplyMachineDefinition))
+	mux.GET(baseRoute+"/vm/:id/define", c.handler((*CloudAPI).handleGetMachineDefinition))
+	mux.PUT(baseRoute+"/vm/:id/define", c.handler((*CloudAPI).handleUpdateMachineDefinition))
 
 	// machine firewall rules
 	machineFWRulesRoute := machineRoute + "/fwrules"
@@ -1016,26 +1036,20 @@ func (c *CloudAPI) SetupHTTP(mux *httprouter.Router) {
 	networkRoute := networksRoute + "/:id"
 	mux.GET(networkRoute, c.handler((*CloudAPI).handleGetNetwork))
 
-	// fabric VLANs
-	fabricVLANsRoute := baseRoute + "/fabrics/:fabric/vlans"
-	mux.GET(fabricVLANsRoute, c.handler((*CloudAPI).handleListFabricVLANs))
-	mux.POST(fabricVLANsRoute, c.handler((*CloudAPI).handleCreateFabricVLAN))
-
-	// fabric VLAN
-	fabricVLANRoute := fabricVLANsRoute + "/:id"
-	mux.GET(fabricVLANRoute, c.handler((*CloudAPI).handleGetFabricVLAN))
-	mux.PUT(fabricVLANRoute, c.handler((*CloudAPI).handleUpdateFabricVLAN))
-	mux.DELETE(fabricVLANRoute, c.handler((*CloudAPI).handleDeleteFabricVLAN))
-
-	// fabric VLAN networks
-	fabricVLANNetworksRoute := fabricVLANRoute + "/networks"
-	mux.GET(fabricVLANNetworksRoute, c.handler((*CloudAPI).handleListFabricNetworks))
-	mux.POST(fabricVLANNetworksRoute, c.handler((*CloudAPI).handleCreateFabricNetwork))
-
-	// fabric VLAN network
-	fabricVLANNetworkRoute := fabricVLANNetworksRoute + "/:network"
-	mux.GET(fabricVLANNetworkRoute, c.handler((*CloudAPI).handleGetFabricNetwork))
-	mux.DELETE(fabricVLANNetworkRoute, c.handler((*CloudAPI).handleDeleteFabricNetwork))
+	// overlay rules
+	overlayRulesRoute := baseRoute + "/node/:node/overlay"
+	mux.GET(overlayRulesRoute, c.handler((*CloudAPI).handleListOverlayRules))
+
+	// overlay rule
+	overlayRuleRoute := overlayRulesRoute + "/:name"
+	mux.GET(overlayRuleRoute, c.handler((*CloudAPI).handleGetOverlayRule))
+	mux.POST(overlayRuleRoute, c.handler((*CloudAPI).handleCreateOverlayRule))
+	mux.PUT(overlayRuleRoute, c.handler((*CloudAPI).handleUpdateOverlayRule))
+	mux.DELETE(overlayRuleRoute, c.handler((*CloudAPI).handleDeleteOverlayRule))
+
+	// overlay networks (the double only supports listing and creating networks on top of overlay rules)
+	mux.GET(baseRoute+"/network", c.handler((*CloudAPI).handleListOverlayNetworks))
+	mux.POST(baseRoute+"/network/:name", c.handler((*CloudAPI).handleCreateOverlayNetwork))
 
 	// services
 	servicesRoute := baseRoute + "/services"
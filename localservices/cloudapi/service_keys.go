@@ -46,7 +46,12 @@ func (c *CloudAPI) CreateKey(keyName, key string) (*cloudapi.Key, error) {
 		}
 	}
 
-	newKey := cloudapi.Key{Name: keyName, Fingerprint: "", Key: key}
+	fingerprint, err := cloudapi.KeyFingerprintMD5(key)
+	if err != nil {
+		return nil, err
+	}
+
+	newKey := cloudapi.Key{Name: keyName, Fingerprint: fingerprint, Key: key}
 	c.keys = append(c.keys, newKey)
 
 	return &newKey, nil
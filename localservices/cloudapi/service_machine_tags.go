@@ -1,91 +1,53 @@
 package cloudapi
 
-import "fmt"
+import (
+	"sort"
+	"time"
+)
 
-// ListMachineTags returns the complete set of tags associated with the specified machine.
-func (c *CloudAPI) ListMachineTags(machineID string) (map[string]string, error) {
-	machine, err := c.GetMachine(machineID)
-	if err != nil {
-		return nil, err
-	}
+// Danube tags are plain names; the double keeps them as the keys of Machine.Tags.
 
-	return machine.Tags, nil
-}
-
-// AddMachineTags adds additional tags to the specified machine.
-// This API lets you append new tags, not overwrite existing tags.
-func (c *CloudAPI) AddMachineTags(machineID string, tags map[string]string) (map[string]string, error) {
+// ListMachineTags returns the tags of the specified machine.
+func (c *CloudAPI) ListMachineTags(machineID string) ([]string, error) {
 	machine, err := c.GetMachine(machineID)
 	if err != nil {
 		return nil, err
 	}
 
-	for tag, value := range tags {
-		if _, present := machine.Tags[tag]; !present {
-			machine.Tags[tag] = value
-		}
-	}
-
-	return machine.Tags, nil
+	return tagList(machine.Tags), nil
 }
 
-// ReplaceMachineTags replaces existing tags for the specified machine.
-// This API lets you overwrite existing tags, not append to existing tags.
-func (c *CloudAPI) ReplaceMachineTags(machineID string, tags map[string]string) (map[string]string, error) {
+// ReplaceMachineTags replaces all tags of the specified machine.
+func (c *CloudAPI) ReplaceMachineTags(machineID string, tags []string) ([]string, error) {
 	machine, err := c.GetMachine(machineID)
 	if err != nil {
 		return nil, err
 	}
 
-	for tag, value := range tags {
-		if _, present := machine.Tags[tag]; present {
-			machine.Tags[tag] = value
-		}
+	machine.Tags = make(map[string]string, len(tags))
+	for _, tag := range tags {
+		machine.Tags[tag] = ""
 	}
+	machine.Updated = time.Now().Format("2013-11-26T19:47:13.448Z")
 
-	return machine.Tags, nil
+	return tagList(machine.Tags), nil
 }
 
-// DeleteMachineTags deletes all tags from the specified machine.
-func (c *CloudAPI) DeleteMachineTags(machineID string) error {
-	machine, err := c.GetMachine(machineID)
-	if err != nil {
-		return err
-	}
-
-	for tag := range machine.Tags {
-		delete(machine.Tags, tag)
-	}
-
-	return nil
-}
-
-func (c *CloudAPI) DeleteMachineTag(machineID, tagKey string) error {
-	machine, err := c.GetMachine(machineID)
-	if err != nil {
-		return err
-	}
-
-	_, present := machine.Tags[tagKey]
-	if !present {
-		return fmt.Errorf(`tag "%s" not found`, tagKey)
+// hasTags tells whether the machine has all the tags.
+func hasTags(m *machine, tags []string) bool {
+	for _, tag := range tags {
+		if _, present := m.Tags[tag]; !present {
+			return false
+		}
 	}
-
-	delete(machine.Tags, tagKey)
-	return nil
+	return true
 }
 
-// GetMachineTag returns the value for a single tag on the specified machine.
-func (c *CloudAPI) GetMachineTag(machineID, tagKey string) (string, error) {
-	machine, err := c.GetMachine(machineID)
-	if err != nil {
-		return "", err
+func tagList(tags map[string]string) []string {
+	list := make([]string, 0, len(tags))
+	for tag := range tags {
+		list = append(list, tag)
 	}
-
-	val, ok := machine.Tags[tagKey]
-	if !ok {
-		return "", fmt.Errorf(`tag "%s" not found`, tagKey)
-	}
-
-	return val, nil
+	sort.Strings(list)
+	return list
 }
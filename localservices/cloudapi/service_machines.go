@@ -192,6 +192,9 @@ func (c *CloudAPI) StartMachine(machineID string) error {
 
 	for _, machine := range c.machines {
 		if machine.Id == machineID {
+			if machine.State == "frozen" {
+				return fmt.Errorf("Machine %s is frozen", machineID)
+			}
 			machine.State = "running"
 			machine.Updated = time.Now().Format("2013-11-26T19:47:13.448Z")
 			return nil
@@ -201,15 +204,15 @@ func (c *CloudAPI) StartMachine(machineID string) error {
 	return fmt.Errorf("Machine %s not found", machineID)
 }
 
-// RebootMachine changes a machine's state to "running" and updates Updated
-func (c *CloudAPI) RebootMachine(machineID string) error {
+// FreezeMachine changes a machine's state to "frozen", which prevents it from being started
+func (c *CloudAPI) FreezeMachine(machineID string) error {
 	if err := c.ProcessFunctionHook(c, machineID); err != nil {
 		return err
 	}
 
 	for _, machine := range c.machines {
 		if machine.Id == machineID {
-			machine.State = "running"
+			machine.State = "frozen"
 			machine.Updated = time.Now().Format("2013-11-26T19:47:13.448Z")
 			return nil
 		}
@@ -218,23 +221,35 @@ func (c *CloudAPI) RebootMachine(machineID string) error {
 	return fmt.Errorf("Machine %s not found", machineID)
 }
 
-// ResizeMachine changes a machine's package to a new size. Unlike the real API,
-// this method lets you downsize machines.
-func (c *CloudAPI) ResizeMachine(machineID, packageName string) error {
-	if err := c.ProcessFunctionHook(c, machineID, packageName); err != nil {
+// UnfreezeMachine changes a frozen machine's state to "stopped"
+func (c *CloudAPI) UnfreezeMachine(machineID string) error {
+	if err := c.ProcessFunctionHook(c, machineID); err != nil {
 		return err
 	}
 
-	mPkg, err := c.GetPackage(packageName)
-	if err != nil {
+	for _, machine := range c.machines {
+		if machine.Id == machineID {
+			if machine.State != "frozen" {
+				return fmt.Errorf("Machine %s is not frozen", machineID)
+			}
+			machine.State = "stopped"
+			machine.Updated = time.Now().Format("2013-11-26T19:47:13.448Z")
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Machine %s not found", machineID)
+}
+
+// RebootMachine changes a machine's state to "running" and updates Updated
+func (c *CloudAPI) RebootMachine(machineID string) error {
+	if err := c.ProcessFunctionHook(c, machineID); err != nil {
 		return err
 	}
 
 	for _, machine := range c.machines {
 		if machine.Id == machineID {
-			machine.Package = packageName
-			machine.Memory = mPkg.Memory
-			machine.Disk = mPkg.Disk
+			machine.State = "running"
 			machine.Updated = time.Now().Format("2013-11-26T19:47:13.448Z")
 			return nil
 		}